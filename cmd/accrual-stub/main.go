@@ -0,0 +1,110 @@
+// Команда accrual-stub запускает поддельную систему начислений для локальной разработки:
+//
+//	accrual-stub -a localhost:8081 -scenarios scenarios.yaml -default-accrual 100
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/arseniy96/bonus-program/internal/accrualstub"
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/services/money"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		host           string
+		scenarios      string
+		logLevel       string
+		rateLimit      int
+		retryAfter     time.Duration
+		latency        time.Duration
+		errorRate      float64
+		defaultAccrual money.Amount
+	)
+	flag.StringVar(&host, "a", "localhost:8081", "stub host with port")
+	flag.StringVar(&scenarios, "scenarios", "", "YAML or JSON file with order scenarios")
+	flag.StringVar(&logLevel, "l", "info", "log level")
+	flag.IntVar(&rateLimit, "rate-limit", 0, "requests per minute before 429, 0 disables the limit")
+	flag.DurationVar(&retryAfter, "retry-after", 0, "Retry-After for 429 responses, defaults to the end of the current minute")
+	flag.DurationVar(&latency, "latency", 0, "delay before every response")
+	flag.Float64Var(&errorRate, "error-rate", 0, "share of requests answered with 500")
+	flag.TextVar(&defaultAccrual, "default-accrual", money.Amount(0),
+		"register unknown orders and process them with this accrual instead of answering 204")
+	flag.Parse()
+
+	if err := logger.Initialize(logLevel); err != nil {
+		return err
+	}
+
+	var config accrualstub.Config
+	if scenarios != "" {
+		var err error
+		if config, err = accrualstub.LoadConfig(scenarios); err != nil {
+			return err
+		}
+	}
+	// флаги важнее файла сценариев
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "rate-limit":
+			config.RateLimit = rateLimit
+		case "retry-after":
+			config.RetryAfter = retryAfter
+		case "latency":
+			config.Latency = latency
+		case "error-rate":
+			config.ErrorRate = errorRate
+		case "default-accrual":
+			config.Default = &accrualstub.Scenario{
+				Progression: []string{accrualstub.StatusRegistered, accrualstub.StatusProcessing, accrualstub.StatusProcessed},
+				Accrual:     defaultAccrual,
+			}
+		}
+	})
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{
+		Addr:    host,
+		Handler: accrualstub.New(config),
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Log.Infow("start accrual stub", "host", host, "orders", len(config.Orders))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+	case err := <-serverErr:
+		return err
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
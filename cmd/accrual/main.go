@@ -0,0 +1,119 @@
+// Команда accrual запускает систему расчёта начислений баллов лояльности. Без адреса базы данных
+// правила и заказы хранятся в памяти процесса.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/caarlos0/env"
+
+	"github.com/arseniy96/bonus-program/internal/accrualengine"
+	"github.com/arseniy96/bonus-program/internal/accrualengine/api"
+	"github.com/arseniy96/bonus-program/internal/logger"
+)
+
+type settings struct {
+	Host         string `env:"RUN_ADDRESS"`
+	DatabaseURI  string `env:"DATABASE_URI"`
+	LoggingLevel string `env:"LOG_LEVEL"`
+	// RateLimit — сколько запросов информации о заказе в минуту обслуживается; 0 — без ограничения
+	RateLimit int `env:"ACCRUAL_RATE_LIMIT"`
+	// CalculationInterval — как часто проверять новые заказы, когда очередь расчёта пуста
+	CalculationInterval time.Duration `env:"ACCRUAL_CALCULATION_INTERVAL"`
+	CalculationBatch    int           `env:"ACCRUAL_CALCULATION_BATCH"`
+	ShutdownTimeout     time.Duration `env:"SHUTDOWN_TIMEOUT"`
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	s := &settings{}
+	flag.StringVar(&s.Host, "a", "localhost:8081", "server host with port")
+	flag.StringVar(&s.DatabaseURI, "d", "", "database connection data, in-memory storage if empty")
+	flag.StringVar(&s.LoggingLevel, "l", "info", "log level")
+	flag.IntVar(&s.RateLimit, "rate-limit", 0, "order info requests per minute, 0 disables the limit")
+	flag.DurationVar(&s.CalculationInterval, "calc-interval", time.Second, "how often to look for new orders")
+	flag.IntVar(&s.CalculationBatch, "calc-batch", 100, "orders calculated at once")
+	flag.DurationVar(&s.ShutdownTimeout, "s", 10*time.Second, "graceful shutdown timeout")
+	flag.Parse()
+	if err := env.Parse(s); err != nil {
+		return err
+	}
+	if s.CalculationBatch < 1 || s.CalculationInterval <= 0 {
+		return errors.New("calc-batch and calc-interval must be positive")
+	}
+
+	if err := logger.Initialize(s.LoggingLevel); err != nil {
+		return err
+	}
+
+	var storage accrualengine.Storage = accrualengine.NewMemoryStorage()
+	if s.DatabaseURI != "" {
+		pg, err := accrualengine.NewPostgresStorage(s.DatabaseURI)
+		if err != nil {
+			return err
+		}
+		defer pg.Close()
+		storage = pg
+	} else {
+		logger.Log.Warn("DATABASE_URI is not set, rules and orders are kept in memory")
+	}
+	engine := accrualengine.New(storage)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	calculationDone := make(chan struct{})
+	go func() {
+		engine.Run(ctx, s.CalculationInterval, s.CalculationBatch)
+		close(calculationDone)
+	}()
+
+	srv := &http.Server{
+		Addr:    s.Host,
+		Handler: api.NewRouter(engine, s.RateLimit),
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Log.Infow("start accrual", "host", s.Host)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	var err error
+	select {
+	case <-ctx.Done():
+		logger.Log.Info("shutdown signal received")
+	case err = <-serverErr:
+		logger.Log.Errorf("server error: %v", err)
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Log.Errorf("server shutdown error: %v", err)
+	}
+	select {
+	case <-calculationDone:
+	case <-shutdownCtx.Done():
+		logger.Log.Error("accrual calculation did not stop in time")
+	}
+
+	logger.Log.Info("accrual stopped")
+	return err
+}
@@ -1,35 +1,221 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/arseniy96/bonus-program/internal/accrualengine"
+	"github.com/arseniy96/bonus-program/internal/accrualstub"
 	"github.com/arseniy96/bonus-program/internal/config"
 	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/metrics"
 	"github.com/arseniy96/bonus-program/internal/router"
 	"github.com/arseniy96/bonus-program/internal/server"
+	"github.com/arseniy96/bonus-program/internal/services/accrual"
+	"github.com/arseniy96/bonus-program/internal/services/authtoken"
+	"github.com/arseniy96/bonus-program/internal/services/breaker"
+	"github.com/arseniy96/bonus-program/internal/services/throttle"
 	"github.com/arseniy96/bonus-program/internal/store"
+	"github.com/arseniy96/bonus-program/internal/tracing"
+)
+
+const (
+	rulesCalculationInterval = time.Second
+	rulesCalculationBatch    = 100
 )
 
 func main() {
 	if err := run(); err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 }
 
 func run() error {
-	settings := config.Initialize()
+	settings, err := config.Initialize()
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if settings.PrintConfig {
+		if err := settings.Print(os.Stdout); err != nil {
+			return err
+		}
+		return settings.Validate()
+	}
 
 	if err := logger.Initialize(settings.LoggingLevel); err != nil {
 		return err
 	}
 
+	shutdownTracing, err := tracing.Init(context.Background(), settings)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), settings.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Log.Errorf("tracing shutdown error: %v", err)
+		}
+	}()
+
 	rep, err := store.NewStore(settings.DatabaseURI)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	// база закрывается последней, когда остановлены и HTTP-сервер, и обработчики заказов
 	defer rep.Close()
+	metrics.RegisterStore(rep.DB.DB, rep.OrderQueueStats)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	keys, err := tokenKeys(settings)
+	if err != nil {
+		return err
+	}
 
-	s := server.NewServer(rep, settings)
-	r := router.NewRouter(s)
+	provider, closeProvider, err := accrualProvider(ctx, settings)
+	if err != nil {
+		return err
+	}
+	defer closeProvider()
+	if settings.AccrualBreakerFailures > 0 {
+		provider = server.NewBreakerProvider(provider, breaker.New(breaker.Config{
+			FailureThreshold: settings.AccrualBreakerFailures,
+			CoolDown:         settings.AccrualBreakerCoolDown,
+			HalfOpenRequests: settings.AccrualBreakerHalfOpenRequests,
+		}))
+	}
 
-	logger.Log.Infow("start server", "host", settings.Host)
-	return r.Run(settings.Host)
+	s := server.NewServer(rep, settings, keys, throttleStore(settings, rep), provider)
+	if err := s.RecoverOrders(ctx); err != nil {
+		return err
+	}
+	handler, err := router.NewRouter(s)
+	if err != nil {
+		return err
+	}
+
+	workersDone := make(chan struct{})
+	go func() {
+		s.OrdersWorker(ctx)
+		close(workersDone)
+	}()
+	cleanupDone := make(chan struct{})
+	go func() {
+		s.CleanupWorker(ctx)
+		close(cleanupDone)
+	}()
+
+	srv := &http.Server{
+		Addr:    settings.Host,
+		Handler: handler,
+	}
+	serverErr := make(chan error, 2)
+	go func() {
+		logger.Log.Infow("start server", "host", settings.Host)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+	var metricsSrv *http.Server
+	if settings.MetricsAddress != "" {
+		metricsSrv = &http.Server{
+			Addr:    settings.MetricsAddress,
+			Handler: router.NewMetricsRouter(),
+		}
+		go func() {
+			logger.Log.Infow("start metrics server", "host", settings.MetricsAddress)
+			if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- fmt.Errorf("metrics server: %w", err)
+			}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		logger.Log.Info("shutdown signal received")
+	case err = <-serverErr:
+		logger.Log.Errorf("server error: %v", err)
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), settings.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Log.Errorf("server shutdown error: %v", err)
+	}
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(shutdownCtx); err != nil {
+			logger.Log.Errorf("metrics server shutdown error: %v", err)
+		}
+	}
+	// базу нельзя закрывать, пока обработчики заказов пишут результаты. Через ShutdownTimeout после
+	// сигнала они сами отменяют опрос системы начислений и возвращают задачи в очередь
+	<-workersDone
+	<-cleanupDone
+
+	logger.Log.Info("server stopped")
+	return err
+}
+
+func throttleStore(settings *config.Settings, rep *store.Database) throttle.Store {
+	if settings.ThrottleStore == config.ThrottleStoreMemory {
+		return throttle.NewMemoryStore()
+	}
+	return rep.ThrottleStore()
+}
+
+// accrualProvider создаёт провайдера начислений из настроек. Возвращаемая функция останавливает
+// расчёт по правилам и закрывает его хранилище.
+func accrualProvider(ctx context.Context, settings *config.Settings) (server.AccrualProvider, func(), error) {
+	switch settings.AccrualProvider {
+	case config.AccrualProviderRules:
+		// правила и заказы хранятся в той же базе в таблицах accrual_*, расчёт идёт в этом же процессе
+		storage, err := accrualengine.NewPostgresStorage(settings.DatabaseURI)
+		if err != nil {
+			return nil, nil, err
+		}
+		engine := accrualengine.New(storage)
+		ctx, cancel := context.WithCancel(ctx)
+		calculationDone := make(chan struct{})
+		go func() {
+			engine.Run(ctx, rulesCalculationInterval, rulesCalculationBatch)
+			close(calculationDone)
+		}()
+		return accrualengine.NewRulesProvider(engine), func() {
+			cancel()
+			<-calculationDone
+			storage.Close()
+		}, nil
+	case config.AccrualProviderFixture:
+		provider, err := accrualstub.LoadFixtureProvider(settings.AccrualFixtureFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return provider, func() {}, nil
+	default:
+		return accrual.NewClient(settings.AccrualHost), func() {}, nil
+	}
+}
+
+func tokenKeys(settings *config.Settings) (*authtoken.Keys, error) {
+	if settings.JWTSigningKeys == "" {
+		logger.Log.Warn("JWT_SIGNING_KEYS is not set, tokens will be signed with a random key " +
+			"and become invalid after restart")
+		return authtoken.GenerateKeys()
+	}
+	return authtoken.NewKeys(settings.JWTSigningKeys, settings.JWTActiveKeyID)
 }
@@ -0,0 +1,25 @@
+BEGIN TRANSACTION;
+
+    CREATE TABLE IF NOT EXISTS accrual_rules(
+        id INT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+        match VARCHAR NOT NULL,
+        reward BIGINT NOT NULL,
+        reward_type VARCHAR NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE UNIQUE INDEX IF NOT EXISTS accrual_rules_match_idx ON accrual_rules(match);
+
+    CREATE TABLE IF NOT EXISTS accrual_orders(
+        number VARCHAR PRIMARY KEY,
+        status VARCHAR NOT NULL,
+        accrual BIGINT NOT NULL DEFAULT 0,
+        goods JSONB NOT NULL,
+        claimed_until TIMESTAMPTZ,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX IF NOT EXISTS accrual_orders_pending_idx ON accrual_orders(created_at)
+        WHERE status IN ('REGISTERED', 'PROCESSING');
+
+COMMIT;
@@ -0,0 +1,17 @@
+BEGIN TRANSACTION;
+
+    CREATE TABLE IF NOT EXISTS order_jobs(
+        id INT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+        order_id INT NOT NULL,
+        attempts INT NOT NULL DEFAULT 0,
+        next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        last_error VARCHAR,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        CONSTRAINT fk_order FOREIGN KEY(order_id) REFERENCES orders(id)
+    );
+
+    CREATE UNIQUE INDEX IF NOT EXISTS order_jobs_order_id_idx on order_jobs(order_id);
+    CREATE INDEX IF NOT EXISTS order_jobs_next_attempt_at_idx on order_jobs(next_attempt_at);
+
+COMMIT;
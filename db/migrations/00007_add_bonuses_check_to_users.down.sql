@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_bonuses_non_negative;
+
+COMMIT;
@@ -0,0 +1,17 @@
+BEGIN TRANSACTION;
+
+-- до атомарного списания баланс мог уйти в минус, поэтому существующие строки сразу не проверяем:
+-- NOT VALID применяет ограничение только к новым записям, а проверяем его, только если таких строк нет
+ALTER TABLE users
+    ADD CONSTRAINT users_bonuses_non_negative CHECK (bonuses >= 0) NOT VALID;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users WHERE bonuses < 0) THEN
+        RAISE WARNING 'users with negative bonuses found, users_bonuses_non_negative is left NOT VALID';
+    ELSE
+        ALTER TABLE users VALIDATE CONSTRAINT users_bonuses_non_negative;
+    END IF;
+END $$;
+
+COMMIT;
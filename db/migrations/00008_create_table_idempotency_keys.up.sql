@@ -0,0 +1,17 @@
+BEGIN TRANSACTION;
+
+    CREATE TABLE IF NOT EXISTS idempotency_keys(
+        id INT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+        key VARCHAR NOT NULL,
+        user_id INT NOT NULL,
+        request_hash VARCHAR NOT NULL,
+        status_code INT,
+        content_type VARCHAR,
+        response_body TEXT,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id)
+    );
+
+    CREATE UNIQUE INDEX IF NOT EXISTS idempotency_keys_user_id_key_idx on idempotency_keys(user_id, key);
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN TRANSACTION;
+
+    ALTER TABLE users
+        ALTER COLUMN bonuses TYPE INT;
+
+    ALTER TABLE bonus_transactions
+        ALTER COLUMN amount TYPE INT;
+
+COMMIT;
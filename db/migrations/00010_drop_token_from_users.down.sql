@@ -0,0 +1,9 @@
+BEGIN TRANSACTION;
+
+    ALTER TABLE users
+        ADD COLUMN IF NOT EXISTS token VARCHAR,
+        ADD COLUMN IF NOT EXISTS token_exp_at TIMESTAMP;
+
+    CREATE INDEX IF NOT EXISTS token_idx on users(token);
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN TRANSACTION;
+
+    DROP INDEX IF EXISTS token_idx;
+
+    ALTER TABLE users
+        DROP COLUMN IF EXISTS token,
+        DROP COLUMN IF EXISTS token_exp_at;
+
+COMMIT;
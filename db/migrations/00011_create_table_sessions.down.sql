@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+    DROP TABLE IF EXISTS refresh_tokens;
+
+    DROP TABLE IF EXISTS sessions;
+
+COMMIT;
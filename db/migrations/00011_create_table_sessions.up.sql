@@ -0,0 +1,27 @@
+BEGIN TRANSACTION;
+
+    CREATE TABLE IF NOT EXISTS sessions(
+        id VARCHAR PRIMARY KEY,
+        user_id INT NOT NULL,
+        user_agent VARCHAR,
+        ip VARCHAR,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        expires_at TIMESTAMP NOT NULL,
+        revoked_at TIMESTAMP,
+        CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id)
+    );
+
+    CREATE INDEX IF NOT EXISTS sessions_user_id_idx on sessions(user_id);
+
+    CREATE TABLE IF NOT EXISTS refresh_tokens(
+        token_hash VARCHAR PRIMARY KEY,
+        session_id VARCHAR NOT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        used_at TIMESTAMP,
+        CONSTRAINT fk_session FOREIGN KEY(session_id) REFERENCES sessions(id) ON DELETE CASCADE
+    );
+
+    CREATE INDEX IF NOT EXISTS refresh_tokens_session_id_idx on refresh_tokens(session_id);
+
+COMMIT;
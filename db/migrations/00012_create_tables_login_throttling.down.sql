@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+    DROP TABLE IF EXISTS failed_logins;
+    DROP TABLE IF EXISTS throttle_lockouts;
+    DROP TABLE IF EXISTS throttle_failures;
+
+COMMIT;
@@ -0,0 +1,28 @@
+BEGIN TRANSACTION;
+
+    CREATE TABLE IF NOT EXISTS throttle_failures(
+        id INT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+        key VARCHAR NOT NULL,
+        failed_at TIMESTAMP NOT NULL
+    );
+
+    CREATE INDEX IF NOT EXISTS throttle_failures_key_idx on throttle_failures(key, failed_at);
+
+    CREATE TABLE IF NOT EXISTS throttle_lockouts(
+        key VARCHAR PRIMARY KEY,
+        lockouts INT NOT NULL,
+        locked_until TIMESTAMP NOT NULL
+    );
+
+    CREATE TABLE IF NOT EXISTS failed_logins(
+        id INT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+        login VARCHAR NOT NULL,
+        ip VARCHAR,
+        user_agent VARCHAR,
+        reason VARCHAR NOT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX IF NOT EXISTS failed_logins_login_idx on failed_logins(login, created_at);
+
+COMMIT;
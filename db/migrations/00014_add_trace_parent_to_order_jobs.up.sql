@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE order_jobs
+    ADD COLUMN IF NOT EXISTS trace_parent VARCHAR;
+
+COMMIT;
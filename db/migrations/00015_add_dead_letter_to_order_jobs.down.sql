@@ -0,0 +1,8 @@
+BEGIN TRANSACTION;
+
+DROP INDEX IF EXISTS order_jobs_dead_lettered_at_idx;
+
+ALTER TABLE order_jobs
+    DROP COLUMN IF EXISTS dead_lettered_at;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE order_jobs
+    ADD COLUMN IF NOT EXISTS dead_lettered_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS order_jobs_dead_lettered_at_idx on order_jobs(dead_lettered_at) WHERE dead_lettered_at IS NOT NULL;
+
+COMMIT;
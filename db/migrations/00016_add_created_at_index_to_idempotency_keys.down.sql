@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+DROP INDEX IF EXISTS idempotency_keys_created_at_idx;
+
+COMMIT;
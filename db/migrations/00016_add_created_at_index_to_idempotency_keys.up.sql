@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_idx on idempotency_keys(created_at);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+DROP INDEX IF EXISTS failed_logins_created_at_idx;
+DROP INDEX IF EXISTS throttle_lockouts_locked_until_idx;
+DROP INDEX IF EXISTS throttle_failures_failed_at_idx;
+
+COMMIT;
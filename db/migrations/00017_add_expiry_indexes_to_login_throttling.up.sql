@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+CREATE INDEX IF NOT EXISTS throttle_failures_failed_at_idx on throttle_failures(failed_at);
+CREATE INDEX IF NOT EXISTS throttle_lockouts_locked_until_idx on throttle_lockouts(locked_until);
+CREATE INDEX IF NOT EXISTS failed_logins_created_at_idx on failed_logins(created_at);
+
+COMMIT;
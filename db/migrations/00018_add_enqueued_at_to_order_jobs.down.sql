@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE order_jobs
+    DROP COLUMN IF EXISTS enqueued_at;
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN TRANSACTION;
+
+-- enqueued_at — с какого момента отсчитывается order_max_age; created_at больше не переписывается при
+-- повторной постановке в очередь. Уже стоящим в очереди задачам отсчёт начинается с момента миграции,
+-- иначе после обновления вся очередь старше order_max_age ушла бы в dead letter при первом опросе.
+ALTER TABLE order_jobs
+    ADD COLUMN IF NOT EXISTS enqueued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+COMMIT;
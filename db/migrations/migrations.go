@@ -0,0 +1,8 @@
+// Package migrations встраивает миграции схемы gophermart в бинарник, чтобы сервис не зависел
+// от рабочей директории и знал, до какой версии должна быть обновлена база.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
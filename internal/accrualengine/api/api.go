@@ -0,0 +1,132 @@
+// Package api — HTTP API системы расчёта начислений в формате, который ожидает accrual.Client gophermart.
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/arseniy96/bonus-program/internal/accrualengine"
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/services/money"
+	"github.com/arseniy96/bonus-program/internal/services/ratelimit"
+)
+
+const maxBodySize = 1 << 20
+
+type RegisterRuleRequest struct {
+	Match      string       `json:"match"`
+	Reward     money.Amount `json:"reward"`
+	RewardType string       `json:"reward_type"`
+}
+
+type RegisterOrderRequest struct {
+	Order string               `json:"order"`
+	Goods []accrualengine.Good `json:"goods"`
+}
+
+type OrderResponse struct {
+	Order   string       `json:"order"`
+	Status  string       `json:"status"`
+	Accrual money.Amount `json:"accrual,omitempty"`
+}
+
+type handlers struct {
+	engine  *accrualengine.Engine
+	limiter *ratelimit.Limiter
+}
+
+// NewRouter возвращает обработчик API. rateLimit ограничивает запросы GET /api/orders/{number} в минуту,
+// 0 — без ограничения.
+func NewRouter(engine *accrualengine.Engine, rateLimit int) http.Handler {
+	h := &handlers{engine: engine, limiter: ratelimit.New(rateLimit)}
+
+	g := gin.New()
+	g.Use(gin.Recovery())
+	g.POST("/api/goods", h.registerRule)
+	g.POST("/api/orders", h.registerOrder)
+	g.GET("/api/orders/:number", h.getOrder)
+	return g
+}
+
+func (h *handlers) registerRule(c *gin.Context) {
+	var req RegisterRuleRequest
+	if !decode(c, &req) {
+		return
+	}
+
+	err := h.engine.RegisterRule(c.Request.Context(), accrualengine.Rule{
+		Match:      req.Match,
+		Reward:     req.Reward,
+		RewardType: req.RewardType,
+	})
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func (h *handlers) registerOrder(c *gin.Context) {
+	var req RegisterOrderRequest
+	if !decode(c, &req) {
+		return
+	}
+
+	if err := h.engine.RegisterOrder(c.Request.Context(), req.Order, req.Goods); err != nil {
+		abort(c, err)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+func (h *handlers) getOrder(c *gin.Context) {
+	if retryAfter, limited := h.limiter.Limited(time.Now()); limited {
+		c.Header("Retry-After", strconv.Itoa(ratelimit.RetryAfterSeconds(retryAfter)))
+		c.String(http.StatusTooManyRequests, "No more than %d requests per minute allowed", h.limiter.Limit())
+		return
+	}
+
+	order, err := h.engine.Order(c.Request.Context(), c.Param("number"))
+	if errors.Is(err, accrualengine.ErrNotFound) {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	resp := OrderResponse{Order: order.Number, Status: order.Status}
+	if order.Status == accrualengine.StatusProcessed {
+		resp.Accrual = order.Accrual
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func decode(c *gin.Context, v interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		c.String(http.StatusBadRequest, "invalid request: %v", err)
+		return false
+	}
+	return true
+}
+
+func abort(c *gin.Context, err error) {
+	var validationErr *accrualengine.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		c.String(http.StatusBadRequest, err.Error())
+	case errors.Is(err, accrualengine.ErrConflict):
+		c.String(http.StatusConflict, err.Error())
+	default:
+		logger.FromContext(c.Request.Context()).Errorf("accrual request error: %v", err)
+		c.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/arseniy96/bonus-program/internal/accrualengine"
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/services/accrual"
+	"github.com/arseniy96/bonus-program/internal/services/money"
+)
+
+func init() {
+	logger.Log = zap.NewNop().Sugar()
+}
+
+func post(t *testing.T, url, body string) int {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestAPI(t *testing.T) {
+	engine := accrualengine.New(accrualengine.NewMemoryStorage())
+	ts := httptest.NewServer(NewRouter(engine, 0))
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "register rule", path: "/api/goods", body: `{"match":"Bork","reward":10,"reward_type":"%"}`, status: http.StatusOK},
+		{name: "duplicate rule", path: "/api/goods", body: `{"match":"Bork","reward":5,"reward_type":"pt"}`, status: http.StatusConflict},
+		{name: "invalid reward type", path: "/api/goods", body: `{"match":"LG","reward":5,"reward_type":"rub"}`, status: http.StatusBadRequest},
+		{name: "unknown field", path: "/api/goods", body: `{"match":"LG","reward":5,"type":"pt"}`, status: http.StatusBadRequest},
+		{
+			name:   "register order",
+			path:   "/api/orders",
+			body:   `{"order":"12345678903","goods":[{"description":"Чайник Bork","price":7000}]}`,
+			status: http.StatusAccepted,
+		},
+		{
+			name:   "duplicate order",
+			path:   "/api/orders",
+			body:   `{"order":"12345678903","goods":[{"description":"Чайник Bork","price":7000}]}`,
+			status: http.StatusConflict,
+		},
+		{
+			name:   "invalid order number",
+			path:   "/api/orders",
+			body:   `{"order":"12345678904","goods":[{"description":"Чайник Bork","price":7000}]}`,
+			status: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.status, post(t, ts.URL+tt.path, tt.body))
+		})
+	}
+
+	client := accrual.NewClient(ts.URL)
+	ctx := context.Background()
+
+	res, err := client.CheckOrder(ctx, "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, accrualengine.StatusRegistered, res.Status)
+	assert.Equal(t, money.Amount(0), res.Accrual)
+
+	_, err = engine.ProcessBatch(ctx, 10)
+	require.NoError(t, err)
+
+	res, err = client.CheckOrder(ctx, "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, accrualengine.StatusProcessed, res.Status)
+	assert.Equal(t, money.Amount(70000), res.Accrual)
+
+	_, err = client.CheckOrder(ctx, "2377225624")
+	assert.ErrorIs(t, err, accrual.ErrOrderNotRegistered)
+}
+
+func TestAPI_RateLimit(t *testing.T) {
+	ts := httptest.NewServer(NewRouter(accrualengine.New(accrualengine.NewMemoryStorage()), 1))
+	defer ts.Close()
+	client := accrual.NewClient(ts.URL)
+
+	_, err := client.CheckOrder(context.Background(), "12345678903")
+	assert.ErrorIs(t, err, accrual.ErrOrderNotRegistered)
+
+	_, err = client.CheckOrder(context.Background(), "12345678903")
+	var tooManyErr *accrual.TooManyRequestsError
+	require.True(t, errors.As(err, &tooManyErr))
+	assert.Equal(t, 1, tooManyErr.Limit)
+}
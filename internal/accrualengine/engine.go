@@ -0,0 +1,164 @@
+// Package accrualengine — система расчёта начислений: правила вознаграждения за товары, регистрация заказов
+// и фоновый расчёт. HTTP API описано в пакете api.
+package accrualengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/services/money"
+	"github.com/arseniy96/bonus-program/internal/services/validations"
+)
+
+const (
+	StatusRegistered = "REGISTERED"
+	StatusInvalid    = "INVALID"
+	StatusProcessing = "PROCESSING"
+	StatusProcessed  = "PROCESSED"
+
+	// CalculationLease — через сколько заказ, взятый в расчёт, снова станет доступен, если расчёт не завершился
+	CalculationLease = time.Minute
+)
+
+var (
+	ErrConflict = errors.New(`already exists`)
+	ErrNotFound = errors.New(`not found`)
+)
+
+// ValidationError — некорректные данные в запросе.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+type Order struct {
+	Number    string
+	Status    string
+	Accrual   money.Amount
+	Goods     []Good
+	CreatedAt time.Time
+}
+
+type Storage interface {
+	// CreateRule возвращает ErrConflict, если правило с таким Match уже есть.
+	CreateRule(context.Context, Rule) error
+	// Rules возвращает правила в порядке регистрации.
+	Rules(context.Context) ([]Rule, error)
+	// CreateOrder возвращает ErrConflict, если заказ уже зарегистрирован.
+	CreateOrder(context.Context, string, []Good) error
+	// FindOrder возвращает ErrNotFound, если заказа нет.
+	FindOrder(context.Context, string) (*Order, error)
+	// ClaimOrders переводит в статус PROCESSING до limit заказов, ожидающих расчёта, и возвращает их.
+	// Заказ, расчёт которого не завершился за lease, возвращается повторно.
+	ClaimOrders(ctx context.Context, limit int, lease time.Duration) ([]Order, error)
+	// FinishOrder сохраняет окончательный статус и начисление.
+	FinishOrder(context.Context, string, string, money.Amount) error
+}
+
+type Engine struct {
+	storage Storage
+}
+
+func New(storage Storage) *Engine {
+	return &Engine{storage: storage}
+}
+
+func (e *Engine) RegisterRule(ctx context.Context, rule Rule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	return e.storage.CreateRule(ctx, rule)
+}
+
+// RegisterOrder принимает заказ к расчёту. Сам расчёт выполняется асинхронно в Run.
+func (e *Engine) RegisterOrder(ctx context.Context, number string, goods []Good) error {
+	if err := validations.LuhnValidate(number); err != nil {
+		return &ValidationError{Err: err}
+	}
+	if len(goods) == 0 {
+		return &ValidationError{Err: errors.New("order has no goods")}
+	}
+	for _, g := range goods {
+		if err := g.Validate(); err != nil {
+			return err
+		}
+	}
+	return e.storage.CreateOrder(ctx, number, goods)
+}
+
+func (e *Engine) Order(ctx context.Context, number string) (*Order, error) {
+	return e.storage.FindOrder(ctx, number)
+}
+
+// Ping проверяет, что хранилище правил и заказов доступно.
+func (e *Engine) Ping(ctx context.Context) error {
+	_, err := e.storage.Rules(ctx)
+	return err
+}
+
+// Run рассчитывает начисления по зарегистрированным заказам, пока не отменят ctx. Пока заказы есть,
+// следующая пачка берётся сразу, иначе — через interval.
+func (e *Engine) Run(ctx context.Context, interval time.Duration, batch int) {
+	for {
+		processed, err := e.ProcessBatch(ctx, batch)
+		if err != nil && ctx.Err() == nil {
+			logger.Log.Errorf("accrual calculation error: %v", err)
+		}
+		if processed == batch && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// ProcessBatch рассчитывает одну пачку заказов и возвращает, сколько заказов взято в расчёт.
+func (e *Engine) ProcessBatch(ctx context.Context, batch int) (int, error) {
+	orders, err := e.storage.ClaimOrders(ctx, batch, CalculationLease)
+	if err != nil {
+		return 0, err
+	}
+	if len(orders) == 0 {
+		return 0, nil
+	}
+	// правила читаются на каждую пачку, чтобы новые правила сразу применялись к ещё не рассчитанным заказам
+	rules, err := e.storage.Rules(ctx)
+	if err != nil {
+		return len(orders), err
+	}
+
+	for _, order := range orders {
+		status, accrual := StatusProcessed, money.Amount(0)
+		total, matched, err := Calculate(rules, order.Goods)
+		switch {
+		case err != nil:
+			logger.Log.Warnw("accrual calculation failed", "order", order.Number, "error", err)
+			status = StatusInvalid
+		case !matched:
+			// ни один товар не участвует в программе лояльности — заказ не принимается к расчёту
+			status = StatusInvalid
+		default:
+			accrual = total
+		}
+
+		if err := e.storage.FinishOrder(ctx, order.Number, status, accrual); err != nil {
+			return len(orders), fmt.Errorf("finish order %s: %w", order.Number, err)
+		}
+		logger.Log.Debugw("order calculated", "order", order.Number, "status", status, "accrual", accrual)
+	}
+	return len(orders), nil
+}
@@ -0,0 +1,84 @@
+package accrualengine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/services/money"
+)
+
+func init() {
+	logger.Log = zap.NewNop().Sugar()
+}
+
+func TestEngine_ProcessBatch(t *testing.T) {
+	ctx := context.Background()
+	engine := New(NewMemoryStorage())
+
+	require.NoError(t, engine.RegisterRule(ctx, Rule{Match: "Bork", Reward: 1000, RewardType: RewardPercent}))
+	assert.ErrorIs(t, engine.RegisterRule(ctx, Rule{Match: "Bork", Reward: 100, RewardType: RewardPoints}), ErrConflict)
+
+	require.NoError(t, engine.RegisterOrder(ctx, "12345678903", []Good{{Description: "Чайник Bork", Price: 700000}}))
+	require.NoError(t, engine.RegisterOrder(ctx, "2377225624", []Good{{Description: "Хлеб", Price: 5000}}))
+	assert.ErrorIs(t, engine.RegisterOrder(ctx, "12345678903", []Good{{Description: "Bork", Price: 1}}), ErrConflict)
+
+	var validationErr *ValidationError
+	assert.ErrorAs(t, engine.RegisterOrder(ctx, "12345678904", []Good{{Description: "Bork", Price: 1}}), &validationErr)
+	assert.ErrorAs(t, engine.RegisterOrder(ctx, "79927398713", nil), &validationErr)
+	assert.ErrorAs(t, engine.RegisterOrder(ctx, "79927398713", []Good{{Description: "Bork", Price: -1}}), &validationErr)
+
+	order, err := engine.Order(ctx, "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, StatusRegistered, order.Status)
+
+	processed, err := engine.ProcessBatch(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, processed)
+
+	order, err = engine.Order(ctx, "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, StatusProcessed, order.Status)
+	assert.Equal(t, money.Amount(70000), order.Accrual)
+
+	order, err = engine.Order(ctx, "2377225624")
+	require.NoError(t, err)
+	assert.Equal(t, StatusInvalid, order.Status)
+
+	// окончательные статусы повторно не рассчитываются
+	processed, err = engine.ProcessBatch(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 0, processed)
+
+	_, err = engine.Order(ctx, "79927398713")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMemoryStorage_ClaimOrders(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	require.NoError(t, storage.CreateOrder(ctx, "12345678903", []Good{{Description: "Bork", Price: 100}}))
+	require.NoError(t, storage.CreateOrder(ctx, "2377225624", []Good{{Description: "Bork", Price: 100}}))
+
+	orders, err := storage.ClaimOrders(ctx, 1, CalculationLease)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "12345678903", orders[0].Number)
+	assert.Equal(t, StatusProcessing, orders[0].Status)
+
+	// взятый в расчёт заказ не выдаётся повторно, пока не истёк lease
+	orders, err = storage.ClaimOrders(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "2377225624", orders[0].Number)
+
+	// lease второго заказа истёк сразу, поэтому он выдаётся снова
+	orders, err = storage.ClaimOrders(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "2377225624", orders[0].Number)
+}
@@ -0,0 +1,114 @@
+package accrualengine
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/arseniy96/bonus-program/internal/services/money"
+)
+
+// MemoryStorage хранит правила и заказы в памяти процесса; подходит для локального запуска и тестов.
+type MemoryStorage struct {
+	mu     sync.Mutex
+	rules  []Rule
+	orders map[string]*memoryOrder
+}
+
+type memoryOrder struct {
+	Order
+	claimedUntil time.Time
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{orders: make(map[string]*memoryOrder)}
+}
+
+func (m *MemoryStorage) CreateRule(_ context.Context, rule Rule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.rules {
+		if r.Match == rule.Match {
+			return ErrConflict
+		}
+	}
+	m.rules = append(m.rules, rule)
+	return nil
+}
+
+func (m *MemoryStorage) Rules(context.Context) ([]Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Rule(nil), m.rules...), nil
+}
+
+func (m *MemoryStorage) CreateOrder(_ context.Context, number string, goods []Good) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.orders[number]; ok {
+		return ErrConflict
+	}
+	m.orders[number] = &memoryOrder{Order: Order{
+		Number:    number,
+		Status:    StatusRegistered,
+		Goods:     append([]Good(nil), goods...),
+		CreatedAt: time.Now(),
+	}}
+	return nil
+}
+
+func (m *MemoryStorage) FindOrder(_ context.Context, number string) (*Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.orders[number]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	order := o.Order
+	return &order, nil
+}
+
+func (m *MemoryStorage) ClaimOrders(_ context.Context, limit int, lease time.Duration) ([]Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var pending []*memoryOrder
+	for _, o := range m.orders {
+		if o.Status == StatusRegistered || (o.Status == StatusProcessing && !now.Before(o.claimedUntil)) {
+			pending = append(pending, o)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].CreatedAt.Before(pending[j].CreatedAt)
+	})
+	if len(pending) > limit {
+		pending = pending[:limit]
+	}
+
+	orders := make([]Order, 0, len(pending))
+	for _, o := range pending {
+		o.Status = StatusProcessing
+		o.claimedUntil = now.Add(lease)
+		orders = append(orders, o.Order)
+	}
+	return orders, nil
+}
+
+func (m *MemoryStorage) FinishOrder(_ context.Context, number, status string, accrual money.Amount) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.orders[number]
+	if !ok {
+		return ErrNotFound
+	}
+	o.Status = status
+	o.Accrual = accrual
+	return nil
+}
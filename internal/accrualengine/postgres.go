@@ -0,0 +1,195 @@
+package accrualengine
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/arseniy96/bonus-program/db/accrual/migrations"
+	"github.com/arseniy96/bonus-program/internal/services/money"
+)
+
+// migrationsTable отличается от таблицы gophermart, чтобы сервисы могли жить в одной базе
+const migrationsTable = "accrual_schema_migrations"
+
+type PostgresStorage struct {
+	DB *sqlx.DB
+}
+
+func NewPostgresStorage(dsn string) (*PostgresStorage, error) {
+	if err := runMigrations(dsn); err != nil {
+		return nil, fmt.Errorf("migrations failed with error: %w", err)
+	}
+	db, err := sqlx.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &PostgresStorage{DB: db}, nil
+}
+
+func (p *PostgresStorage) Close() error {
+	return p.DB.Close()
+}
+
+func runMigrations(dsn string) error {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return err
+	}
+	return migrateUp(db)
+}
+
+// migrateUp применяет встроенные миграции и закрывает db вместе с migrate.
+func migrateUp(db *sql.DB) error {
+	driver, err := postgres.WithInstance(db, &postgres.Config{MigrationsTable: migrationsTable})
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("failed to get a migrate driver: %w", err)
+	}
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		driver.Close()
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+	m, err := migrate.NewWithInstance("iofs", src, "postgres", driver)
+	if err != nil {
+		driver.Close()
+		return fmt.Errorf("failed to get a new migrate instance: %w", err)
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresStorage) CreateRule(ctx context.Context, rule Rule) error {
+	_, err := p.DB.ExecContext(ctx,
+		`INSERT INTO accrual_rules(match, reward, reward_type) VALUES($1, $2, $3)`,
+		rule.Match, rule.Reward, rule.RewardType)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (p *PostgresStorage) Rules(ctx context.Context) ([]Rule, error) {
+	rows, err := p.DB.QueryContext(ctx, `SELECT match, reward, reward_type FROM accrual_rules ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []Rule
+	for rows.Next() {
+		var r Rule
+		if err := rows.Scan(&r.Match, &r.Reward, &r.RewardType); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+func (p *PostgresStorage) CreateOrder(ctx context.Context, number string, goods []Good) error {
+	data, err := json.Marshal(goods)
+	if err != nil {
+		return err
+	}
+	_, err = p.DB.ExecContext(ctx,
+		`INSERT INTO accrual_orders(number, status, goods) VALUES($1, $2, $3)`,
+		number, StatusRegistered, data)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (p *PostgresStorage) FindOrder(ctx context.Context, number string) (*Order, error) {
+	var (
+		order Order
+		goods []byte
+	)
+	err := p.DB.QueryRowContext(ctx,
+		`SELECT number, status, accrual, goods, created_at FROM accrual_orders WHERE number=$1`,
+		number).Scan(&order.Number, &order.Status, &order.Accrual, &goods, &order.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(goods, &order.Goods); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// ClaimOrders блокирует строки через SKIP LOCKED, поэтому несколько экземпляров сервиса не возьмут
+// один и тот же заказ.
+func (p *PostgresStorage) ClaimOrders(ctx context.Context, limit int, lease time.Duration) ([]Order, error) {
+	rows, err := p.DB.QueryContext(ctx,
+		`UPDATE accrual_orders SET status=$1, claimed_until=CURRENT_TIMESTAMP + make_interval(secs => $2)
+		WHERE number IN (
+			SELECT number FROM accrual_orders
+			WHERE status=$3 OR (status=$1 AND claimed_until < CURRENT_TIMESTAMP)
+			ORDER BY created_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED)
+		RETURNING number, status, goods, created_at`,
+		StatusProcessing, lease.Seconds(), StatusRegistered, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []Order
+	for rows.Next() {
+		var (
+			order Order
+			goods []byte
+		)
+		if err := rows.Scan(&order.Number, &order.Status, &goods, &order.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(goods, &order.Goods); err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+func (p *PostgresStorage) FinishOrder(ctx context.Context, number, status string, accrual money.Amount) error {
+	res, err := p.DB.ExecContext(ctx,
+		`UPDATE accrual_orders SET status=$1, accrual=$2, claimed_until=NULL WHERE number=$3`,
+		status, accrual, number)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation
+}
@@ -0,0 +1,152 @@
+package accrualengine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arseniy96/bonus-program/internal/services/money"
+)
+
+// newMockStorage возвращает PostgresStorage поверх sqlmock. Запросы сверяются по точному тексту.
+func newMockStorage(t *testing.T) (*PostgresStorage, sqlmock.Sqlmock) {
+	t.Helper()
+	conn, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+		conn.Close()
+	})
+	return &PostgresStorage{DB: sqlx.NewDb(conn, "pgx")}, mock
+}
+
+func TestMigrateUp(t *testing.T) {
+	tests := []struct {
+		name    string
+		dirty   bool
+		wantErr bool
+	}{
+		{name: "schema is up to date"},
+		{name: "dirty schema", dirty: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			require.NoError(t, err)
+
+			mock.ExpectQuery(`SELECT CURRENT_DATABASE()`).
+				WillReturnRows(sqlmock.NewRows([]string{"current_database"}).AddRow("accrual"))
+			mock.ExpectQuery(`SELECT CURRENT_SCHEMA()`).
+				WillReturnRows(sqlmock.NewRows([]string{"current_schema"}).AddRow("public"))
+			mock.ExpectExec(`SELECT pg_advisory_lock($1)`).WithArgs(sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			// версии схемы хранятся в своей таблице, а не в schema_migrations gophermart
+			mock.ExpectQuery(`SELECT COUNT(1) FROM information_schema.tables WHERE table_schema = $1 AND table_name = $2 LIMIT 1`).
+				WithArgs("public", "accrual_schema_migrations").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			mock.ExpectExec(`SELECT pg_advisory_unlock($1)`).WithArgs(sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+
+			mock.ExpectExec(`SELECT pg_advisory_lock($1)`).WithArgs(sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(`SELECT version, dirty FROM "public"."accrual_schema_migrations" LIMIT 1`).
+				WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(1, tt.dirty))
+			mock.ExpectExec(`SELECT pg_advisory_unlock($1)`).WithArgs(sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectClose()
+
+			err = migrateUp(db)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresStorage_CreateRule(t *testing.T) {
+	p, mock := newMockStorage(t)
+	rule := Rule{Match: "Bork", Reward: 1000, RewardType: RewardPercent}
+	mock.ExpectExec(`INSERT INTO accrual_rules(match, reward, reward_type) VALUES($1, $2, $3)`).
+		WithArgs("Bork", int64(1000), RewardPercent).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO accrual_rules(match, reward, reward_type) VALUES($1, $2, $3)`).
+		WithArgs("Bork", int64(1000), RewardPercent).
+		WillReturnError(&pgconn.PgError{Code: pgerrcode.UniqueViolation})
+
+	require.NoError(t, p.CreateRule(context.Background(), rule))
+	assert.ErrorIs(t, p.CreateRule(context.Background(), rule), ErrConflict)
+}
+
+func TestPostgresStorage_FindOrder(t *testing.T) {
+	p, mock := newMockStorage(t)
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT number, status, accrual, goods, created_at FROM accrual_orders WHERE number=$1`).
+		WithArgs("12345678903").
+		WillReturnRows(sqlmock.NewRows([]string{"number", "status", "accrual", "goods", "created_at"}).
+			AddRow("12345678903", StatusProcessed, int64(70000), []byte(`[{"description":"Чайник Bork","price":7000}]`), createdAt))
+	mock.ExpectQuery(`SELECT number, status, accrual, goods, created_at FROM accrual_orders WHERE number=$1`).
+		WithArgs("2377225624").
+		WillReturnRows(sqlmock.NewRows([]string{"number", "status", "accrual", "goods", "created_at"}))
+
+	order, err := p.FindOrder(context.Background(), "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, &Order{
+		Number:    "12345678903",
+		Status:    StatusProcessed,
+		Accrual:   70000,
+		Goods:     []Good{{Description: "Чайник Bork", Price: 700000}},
+		CreatedAt: createdAt,
+	}, order)
+
+	_, err = p.FindOrder(context.Background(), "2377225624")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPostgresStorage_ClaimOrders(t *testing.T) {
+	p, mock := newMockStorage(t)
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// берутся новые заказы и заказы с истёкшей арендой, занятые другими экземплярами пропускаются
+	mock.ExpectQuery(`UPDATE accrual_orders SET status=$1, claimed_until=CURRENT_TIMESTAMP + make_interval(secs => $2)
+		WHERE number IN (
+			SELECT number FROM accrual_orders
+			WHERE status=$3 OR (status=$1 AND claimed_until < CURRENT_TIMESTAMP)
+			ORDER BY created_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED)
+		RETURNING number, status, goods, created_at`).
+		WithArgs(StatusProcessing, float64(30), StatusRegistered, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"number", "status", "goods", "created_at"}).
+			AddRow("12345678903", StatusProcessing, []byte(`[{"description":"Хлеб","price":50}]`), createdAt))
+
+	orders, err := p.ClaimOrders(context.Background(), 10, 30*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, []Order{{
+		Number:    "12345678903",
+		Status:    StatusProcessing,
+		Goods:     []Good{{Description: "Хлеб", Price: 5000}},
+		CreatedAt: createdAt,
+	}}, orders)
+}
+
+func TestPostgresStorage_FinishOrder(t *testing.T) {
+	p, mock := newMockStorage(t)
+	mock.ExpectExec(`UPDATE accrual_orders SET status=$1, accrual=$2, claimed_until=NULL WHERE number=$3`).
+		WithArgs(StatusProcessed, int64(70000), "12345678903").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accrual_orders SET status=$1, accrual=$2, claimed_until=NULL WHERE number=$3`).
+		WithArgs(StatusInvalid, int64(0), "2377225624").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, p.FinishOrder(context.Background(), "12345678903", StatusProcessed, money.Amount(70000)))
+	assert.ErrorIs(t, p.FinishOrder(context.Background(), "2377225624", StatusInvalid, 0), ErrNotFound)
+}
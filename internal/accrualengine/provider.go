@@ -0,0 +1,44 @@
+package accrualengine
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/arseniy96/bonus-program/internal/services/accrual"
+)
+
+// RulesProvider считает начисления в процессе gophermart движком Engine, без HTTP-запросов.
+// Правила и заказы с товарами регистрируются в хранилище движка, например через API cmd/accrual,
+// работающего с той же базой.
+type RulesProvider struct {
+	engine *Engine
+}
+
+func NewRulesProvider(engine *Engine) *RulesProvider {
+	return &RulesProvider{engine: engine}
+}
+
+func (p *RulesProvider) CheckOrder(ctx context.Context, orderNumber string) (*accrual.GetOrderResponse, error) {
+	order, err := p.engine.Order(ctx, orderNumber)
+	if errors.Is(err, ErrNotFound) {
+		return nil, accrual.ErrOrderNotRegistered
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := &accrual.GetOrderResponse{Order: order.Number, Status: order.Status}
+	if order.Status == StatusProcessed {
+		res.Accrual = order.Accrual
+	}
+	return res, nil
+}
+
+func (p *RulesProvider) PausedFor() time.Duration {
+	return 0
+}
+
+func (p *RulesProvider) Ping(ctx context.Context) error {
+	return p.engine.Ping(ctx)
+}
@@ -0,0 +1,33 @@
+package accrualengine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arseniy96/bonus-program/internal/services/accrual"
+)
+
+func TestRulesProvider(t *testing.T) {
+	ctx := context.Background()
+	engine := New(NewMemoryStorage())
+	require.NoError(t, engine.RegisterRule(ctx, Rule{Match: "Bork", Reward: 1000, RewardType: RewardPercent}))
+	require.NoError(t, engine.RegisterOrder(ctx, "12345678903", []Good{{Description: "Чайник Bork", Price: 700000}}))
+
+	p := NewRulesProvider(engine)
+	res, err := p.CheckOrder(ctx, "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, &accrual.GetOrderResponse{Order: "12345678903", Status: accrual.OrderStatusRegistered}, res)
+
+	_, err = engine.ProcessBatch(ctx, 10)
+	require.NoError(t, err)
+	res, err = p.CheckOrder(ctx, "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, &accrual.GetOrderResponse{Order: "12345678903", Status: accrual.OrderStatusProcessed, Accrual: 70000}, res)
+
+	_, err = p.CheckOrder(ctx, "2377225624")
+	assert.ErrorIs(t, err, accrual.ErrOrderNotRegistered)
+	assert.NoError(t, p.Ping(ctx))
+}
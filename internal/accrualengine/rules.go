@@ -0,0 +1,112 @@
+package accrualengine
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/arseniy96/bonus-program/internal/services/money"
+)
+
+const (
+	// RewardPercent — вознаграждение в процентах от цены товара
+	RewardPercent = "%"
+	// RewardPoints — фиксированное вознаграждение в баллах за товар
+	RewardPoints = "pt"
+	// maxRewardPercent — больше цены товара начислить нельзя
+	maxRewardPercent = 100 * money.Scale
+)
+
+// Rule — правило вознаграждения: товары, в описании которых встречается Match, приносят Reward.
+type Rule struct {
+	Match      string
+	Reward     money.Amount
+	RewardType string
+}
+
+type Good struct {
+	Description string       `json:"description"`
+	Price       money.Amount `json:"price"`
+}
+
+func (r Rule) Validate() error {
+	var errs []error
+	if strings.TrimSpace(r.Match) == "" {
+		errs = append(errs, errors.New("match is required"))
+	}
+	switch r.RewardType {
+	case RewardPoints:
+		if r.Reward <= 0 {
+			errs = append(errs, errors.New("reward must be positive"))
+		}
+	case RewardPercent:
+		if r.Reward <= 0 || r.Reward > maxRewardPercent {
+			errs = append(errs, errors.New("percent reward must be in (0, 100]"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("reward_type must be %q or %q", RewardPercent, RewardPoints))
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Err: errors.Join(errs...)}
+	}
+	return nil
+}
+
+func (g Good) Validate() error {
+	if strings.TrimSpace(g.Description) == "" {
+		return &ValidationError{Err: errors.New("good description is required")}
+	}
+	if g.Price < 0 {
+		return &ValidationError{Err: fmt.Errorf("price of %q must not be negative", g.Description)}
+	}
+	return nil
+}
+
+func (r Rule) matches(g Good) bool {
+	return strings.Contains(strings.ToLower(g.Description), strings.ToLower(r.Match))
+}
+
+// rewardFor считает вознаграждение за товар; проценты округляются до копеек.
+func (r Rule) rewardFor(g Good) money.Amount {
+	if r.RewardType == RewardPoints {
+		return r.Reward
+	}
+	// price * percent / 100, где percent тоже хранится в сотых долях; FloatString округляет до целых копеек
+	reward := new(big.Rat).SetFrac(
+		new(big.Int).Mul(big.NewInt(g.Price.Cents()), big.NewInt(r.Reward.Cents())),
+		big.NewInt(100*money.Scale))
+	cents, err := strconv.ParseInt(reward.FloatString(0), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return money.FromCents(cents)
+}
+
+// Calculate считает начисление за заказ. Для каждого товара выбирается самое точное правило — с самой
+// длинной строкой Match, при равной длине — зарегистрированное раньше. Второй результат false, если
+// ни одно правило не подошло ни к одному товару.
+func Calculate(rules []Rule, goods []Good) (money.Amount, bool, error) {
+	var total money.Amount
+	matched := false
+	for _, g := range goods {
+		best := -1
+		for i, r := range rules {
+			if r.matches(g) && (best < 0 || len(r.Match) > len(rules[best].Match)) {
+				best = i
+			}
+		}
+		if best < 0 {
+			continue
+		}
+
+		matched = true
+		var err error
+		total, err = total.Add(rules[best].rewardFor(g))
+		if err != nil {
+			return 0, false, err
+		}
+	}
+	return total, matched, nil
+}
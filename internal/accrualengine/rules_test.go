@@ -0,0 +1,86 @@
+package accrualengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arseniy96/bonus-program/internal/services/money"
+)
+
+func TestCalculate(t *testing.T) {
+	rules := []Rule{
+		{Match: "Bork", Reward: 1000, RewardType: RewardPercent},
+		{Match: "Bork Kettle", Reward: 50000, RewardType: RewardPoints},
+		{Match: "LG", Reward: 750, RewardType: RewardPercent},
+	}
+	tests := []struct {
+		name        string
+		goods       []Good
+		want        money.Amount
+		wantMatched bool
+	}{
+		{
+			name:        "percent reward",
+			goods:       []Good{{Description: "Чайник Bork", Price: 700000}},
+			want:        70000,
+			wantMatched: true,
+		},
+		{
+			name:        "longest match wins",
+			goods:       []Good{{Description: "bork kettle K780", Price: 700000}},
+			want:        50000,
+			wantMatched: true,
+		},
+		{
+			name: "rewards are summed and rounded to cents",
+			goods: []Good{
+				{Description: "Стиральная машинка LG", Price: 3333},
+				{Description: "Чайник Bork", Price: 1000},
+				{Description: "Хлеб", Price: 5000},
+			},
+			// 7.5% от 33.33 = 2.499… → 2.50, плюс 10% от 10
+			want:        350,
+			wantMatched: true,
+		},
+		{
+			name:  "no goods match",
+			goods: []Good{{Description: "Хлеб", Price: 5000}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, matched, err := Calculate(rules, tt.goods)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantMatched, matched)
+		})
+	}
+}
+
+func TestRule_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "points", rule: Rule{Match: "Bork", Reward: 100, RewardType: RewardPoints}},
+		{name: "percent", rule: Rule{Match: "Bork", Reward: 10000, RewardType: RewardPercent}},
+		{name: "empty match", rule: Rule{Match: " ", Reward: 100, RewardType: RewardPoints}, wantErr: true},
+		{name: "percent over 100", rule: Rule{Match: "Bork", Reward: 10001, RewardType: RewardPercent}, wantErr: true},
+		{name: "zero reward", rule: Rule{Match: "Bork", RewardType: RewardPoints}, wantErr: true},
+		{name: "unknown type", rule: Rule{Match: "Bork", Reward: 100, RewardType: "rub"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			var validationErr *ValidationError
+			assert.ErrorAs(t, err, &validationErr)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package accrualstub
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadConfig читает сценарии из YAML- или JSON-файла.
+func LoadConfig(path string) (Config, error) {
+	var config Config
+	if err := decodeFile(path, "scenarios", &config); err != nil {
+		return config, err
+	}
+	return config, config.Validate()
+}
+
+// decodeFile разбирает YAML- или JSON-файл в v; неизвестные ключи считаются ошибкой, чтобы опечатка
+// в файле не проходила молча. what подставляется в текст ошибки.
+func decodeFile(path, what string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", what, err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse %s %s: %w", what, path, err)
+	}
+	return nil
+}
+
+func validStatus(status string) bool {
+	switch status {
+	case StatusRegistered, StatusProcessing, StatusProcessed, StatusInvalid:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c Config) Validate() error {
+	if c.ErrorRate < 0 || c.ErrorRate > 1 {
+		return fmt.Errorf("error_rate must be between 0 and 1, got %v", c.ErrorRate)
+	}
+	if c.Default != nil {
+		if err := c.Default.Validate(); err != nil {
+			return fmt.Errorf("default: %w", err)
+		}
+	}
+	for number, scenario := range c.Orders {
+		if err := scenario.Validate(); err != nil {
+			return fmt.Errorf("orders.%s: %w", number, err)
+		}
+	}
+	return nil
+}
+
+// Validate проверяет, что в сценарии только известные статусы.
+func (sc Scenario) Validate() error {
+	for _, status := range sc.Progression {
+		if !validStatus(status) {
+			return fmt.Errorf("unknown status %q", status)
+		}
+	}
+	if sc.FailFirst < 0 {
+		return errors.New("fail_first must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package accrualstub
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/arseniy96/bonus-program/internal/services/accrual"
+	"github.com/arseniy96/bonus-program/internal/services/money"
+)
+
+// FixtureProvider отвечает gophermart заранее заданными результатами расчёта без HTTP-запросов;
+// о заказах не из списка он не знает.
+type FixtureProvider struct {
+	orders map[string]accrual.GetOrderResponse
+}
+
+func NewFixtureProvider(orders map[string]accrual.GetOrderResponse) *FixtureProvider {
+	fixtures := make(map[string]accrual.GetOrderResponse, len(orders))
+	for number, res := range orders {
+		res.Order = number
+		fixtures[number] = res
+	}
+	return &FixtureProvider{orders: fixtures}
+}
+
+type fixtureFile struct {
+	Orders map[string]struct {
+		Status  string       `yaml:"status"`
+		Accrual money.Amount `yaml:"accrual"`
+	} `yaml:"orders"`
+}
+
+// LoadFixtureProvider читает результаты расчёта из YAML- или JSON-файла вида
+//
+//	orders:
+//	  "12345678903": {status: PROCESSED, accrual: 729.98}
+func LoadFixtureProvider(path string) (*FixtureProvider, error) {
+	var file fixtureFile
+	if err := decodeFile(path, "accrual fixture", &file); err != nil {
+		return nil, err
+	}
+
+	orders := make(map[string]accrual.GetOrderResponse, len(file.Orders))
+	for number, order := range file.Orders {
+		if !validStatus(order.Status) {
+			return nil, fmt.Errorf("accrual fixture: order %s has unknown status %q", number, order.Status)
+		}
+		orders[number] = accrual.GetOrderResponse{Status: order.Status, Accrual: order.Accrual}
+	}
+	return NewFixtureProvider(orders), nil
+}
+
+func (p *FixtureProvider) CheckOrder(_ context.Context, orderNumber string) (*accrual.GetOrderResponse, error) {
+	res, ok := p.orders[orderNumber]
+	if !ok {
+		return nil, accrual.ErrOrderNotRegistered
+	}
+	return &res, nil
+}
+
+func (p *FixtureProvider) PausedFor() time.Duration {
+	return 0
+}
+
+func (p *FixtureProvider) Ping(context.Context) error {
+	return nil
+}
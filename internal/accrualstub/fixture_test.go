@@ -0,0 +1,58 @@
+package accrualstub
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arseniy96/bonus-program/internal/services/accrual"
+)
+
+func TestFixtureProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+orders:
+  "12345678903": {status: PROCESSED, accrual: 729.98}
+  "79927398713": {status: PROCESSED, accrual: "0.29"}
+  "2377225624": {status: PROCESSING}
+`), 0o600))
+
+	p, err := LoadFixtureProvider(path)
+	require.NoError(t, err)
+
+	res, err := p.CheckOrder(context.Background(), "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, &accrual.GetOrderResponse{Order: "12345678903", Status: accrual.OrderStatusProcessed, Accrual: 72998}, res)
+
+	res, err = p.CheckOrder(context.Background(), "79927398713")
+	require.NoError(t, err)
+	assert.Equal(t, &accrual.GetOrderResponse{Order: "79927398713", Status: accrual.OrderStatusProcessed, Accrual: 29}, res)
+
+	res, err = p.CheckOrder(context.Background(), "2377225624")
+	require.NoError(t, err)
+	assert.Equal(t, &accrual.GetOrderResponse{Order: "2377225624", Status: accrual.OrderStatusProcessing}, res)
+
+	_, err = p.CheckOrder(context.Background(), "4561261212345467")
+	assert.ErrorIs(t, err, accrual.ErrOrderNotRegistered)
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "unknown status", content: `orders: {"1": {status: DONE}}`, wantErr: `unknown status "DONE"`},
+		{name: "unknown field", content: `orders: {"1": {status: PROCESSED, acrual: 10}}`, wantErr: "acrual"},
+		{name: "invalid amount", content: `orders: {"1": {status: PROCESSED, accrual: ten}}`, wantErr: "invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+			_, err := LoadFixtureProvider(path)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
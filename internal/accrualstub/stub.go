@@ -0,0 +1,226 @@
+// Package accrualstub — поддельная система начислений для локального запуска и тестов. Stub реализует
+// http.Handler, поэтому в тестах его можно запустить через httptest.NewServer.
+package accrualstub
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+
+	"github.com/arseniy96/bonus-program/internal/services/money"
+	"github.com/arseniy96/bonus-program/internal/services/ratelimit"
+)
+
+const (
+	StatusRegistered = "REGISTERED"
+	StatusInvalid    = "INVALID"
+	StatusProcessing = "PROCESSING"
+	StatusProcessed  = "PROCESSED"
+)
+
+// Scenario описывает ответы системы начислений по одному заказу. Каждый запрос возвращает следующий статус
+// из Progression, последний статус повторяется. Начисление Accrual возвращается только со статусом PROCESSED.
+type Scenario struct {
+	Progression []string     `json:"progression" yaml:"progression"`
+	Accrual     money.Amount `json:"accrual" yaml:"accrual"`
+	// Latency добавляется к общей задержке Config.Latency
+	Latency time.Duration `json:"latency" yaml:"latency"`
+	// FailFirst — сколько первых запросов по заказу завершаются ошибкой 500
+	FailFirst int `json:"fail_first" yaml:"fail_first"`
+}
+
+type Config struct {
+	// RateLimit — сколько запросов в минуту обслуживается, остальные получают 429; 0 — без ограничения
+	RateLimit int `json:"rate_limit" yaml:"rate_limit"`
+	// RetryAfter — значение заголовка Retry-After; по умолчанию — время до конца текущей минуты
+	RetryAfter time.Duration `json:"retry_after" yaml:"retry_after"`
+	// Latency — задержка перед каждым ответом
+	Latency time.Duration `json:"latency" yaml:"latency"`
+	// ErrorRate — доля запросов, которые случайно завершаются ошибкой 500
+	ErrorRate float64 `json:"error_rate" yaml:"error_rate"`
+	// Default — сценарий для заказов, которых нет в Orders; если не задан, на них отвечаем 204
+	Default *Scenario           `json:"default" yaml:"default"`
+	Orders  map[string]Scenario `json:"orders" yaml:"orders"`
+}
+
+type OrderResponse struct {
+	Order   string       `json:"order"`
+	Status  string       `json:"status"`
+	Accrual money.Amount `json:"accrual,omitempty"`
+}
+
+type Stub struct {
+	config Config
+	engine *gin.Engine
+
+	mu        sync.Mutex
+	scenarios map[string]Scenario
+	// requests — все запросы по заказу, attempts — без отклонённых ограничением частоты,
+	// progress — сколько раз по заказу вернули статус
+	requests map[string]int
+	attempts map[string]int
+	progress map[string]int
+	limiter  *ratelimit.Limiter
+}
+
+func New(config Config) *Stub {
+	s := &Stub{
+		config:  config,
+		limiter: ratelimit.New(config.RateLimit),
+	}
+	s.Reset()
+
+	g := gin.New()
+	g.Use(gin.Recovery())
+	g.GET("/api/orders/:number", s.getOrder)
+	// управление сценариями во время работы, например из e2e-тестов
+	g.PUT("/stub/orders/:number", s.putScenario)
+	g.POST("/stub/reset", s.reset)
+	s.engine = g
+
+	return s
+}
+
+func (s *Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.engine.ServeHTTP(w, r)
+}
+
+// SetScenario задаёт сценарий для заказа и сбрасывает счётчик запросов по нему.
+func (s *Stub) SetScenario(number string, scenario Scenario) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scenarios[number] = scenario
+	delete(s.requests, number)
+	delete(s.attempts, number)
+	delete(s.progress, number)
+}
+
+// Requests возвращает, сколько раз запрашивали заказ, включая ответы 429 и 500.
+func (s *Stub) Requests(number string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[number]
+}
+
+// Reset возвращает сценарии из конфигурации и обнуляет счётчики запросов.
+func (s *Stub) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scenarios = make(map[string]Scenario, len(s.config.Orders))
+	for number, scenario := range s.config.Orders {
+		s.scenarios[number] = scenario
+	}
+	s.requests = make(map[string]int)
+	s.attempts = make(map[string]int)
+	s.progress = make(map[string]int)
+	s.limiter.Reset()
+}
+
+func (s *Stub) getOrder(c *gin.Context) {
+	number := c.Param("number")
+
+	s.mu.Lock()
+	s.requests[number]++
+	retryAfter, limited := s.limit(time.Now())
+	scenario, known := s.scenario(number)
+	s.mu.Unlock()
+
+	if limited {
+		c.Header("Retry-After", strconv.Itoa(ratelimit.RetryAfterSeconds(retryAfter)))
+		c.String(http.StatusTooManyRequests, "No more than %d requests per minute allowed", s.config.RateLimit)
+		return
+	}
+
+	if latency := s.config.Latency + scenario.Latency; latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+
+	// ответы 429 и 500 не двигают заказ по статусам
+	s.mu.Lock()
+	s.attempts[number]++
+	failed := s.attempts[number] <= scenario.FailFirst ||
+		(s.config.ErrorRate > 0 && rand.Float64() < s.config.ErrorRate)
+	step := s.progress[number]
+	if !failed {
+		s.progress[number]++
+	}
+	s.mu.Unlock()
+
+	if failed {
+		c.String(http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if !known || len(scenario.Progression) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	if step >= len(scenario.Progression) {
+		step = len(scenario.Progression) - 1
+	}
+	resp := OrderResponse{Order: number, Status: scenario.Progression[step]}
+	if resp.Status == StatusProcessed {
+		resp.Accrual = scenario.Accrual
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// scenario возвращает сценарий заказа или сценарий по умолчанию. Вызывается под s.mu.
+func (s *Stub) scenario(number string) (Scenario, bool) {
+	if scenario, ok := s.scenarios[number]; ok {
+		return scenario, true
+	}
+	if s.config.Default != nil {
+		return *s.config.Default, true
+	}
+	return Scenario{}, false
+}
+
+// limit возвращает, сколько ждать, если лимит запросов в минуту исчерпан. Config.RetryAfter
+// заменяет время до конца окна.
+func (s *Stub) limit(now time.Time) (time.Duration, bool) {
+	retryAfter, limited := s.limiter.Limited(now)
+	if limited && s.config.RetryAfter > 0 {
+		return s.config.RetryAfter, true
+	}
+	return retryAfter, limited
+}
+
+func (s *Stub) putScenario(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	// сценарий разбирается как YAML, чтобы задержки в JSON можно было писать строкой: "latency": "1s"
+	var scenario Scenario
+	if err := yaml.Unmarshal(body, &scenario); err != nil {
+		c.String(http.StatusBadRequest, fmt.Sprintf("invalid scenario: %v", err))
+		return
+	}
+	if err := scenario.Validate(); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	s.SetScenario(c.Param("number"), scenario)
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Stub) reset(c *gin.Context) {
+	s.Reset()
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,172 @@
+package accrualstub
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/services/accrual"
+	"github.com/arseniy96/bonus-program/internal/services/money"
+)
+
+func init() {
+	logger.Log = zap.NewNop().Sugar()
+}
+
+func TestStub_Progression(t *testing.T) {
+	stub := New(Config{
+		Orders: map[string]Scenario{
+			"12345678903": {Progression: []string{StatusRegistered, StatusProcessing, StatusProcessed}, Accrual: 72998},
+			"2377225624":  {Progression: []string{StatusInvalid}},
+			"79927398713": {Progression: []string{StatusProcessed}, Accrual: 1000, FailFirst: 2},
+		},
+	})
+	ts := httptest.NewServer(stub)
+	defer ts.Close()
+	client := accrual.NewClient(ts.URL)
+	ctx := context.Background()
+
+	for _, want := range []string{StatusRegistered, StatusProcessing, StatusProcessed, StatusProcessed} {
+		res, err := client.CheckOrder(ctx, "12345678903")
+		require.NoError(t, err)
+		assert.Equal(t, want, res.Status)
+	}
+	res, err := client.CheckOrder(ctx, "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, money.Amount(72998), res.Accrual)
+
+	res, err = client.CheckOrder(ctx, "2377225624")
+	require.NoError(t, err)
+	assert.Equal(t, StatusInvalid, res.Status)
+
+	for i := 0; i < 2; i++ {
+		_, err = client.CheckOrder(ctx, "79927398713")
+		assert.Error(t, err)
+	}
+	res, err = client.CheckOrder(ctx, "79927398713")
+	require.NoError(t, err)
+	assert.Equal(t, StatusProcessed, res.Status)
+	assert.Equal(t, 3, stub.Requests("79927398713"))
+
+	_, err = client.CheckOrder(ctx, "4561261212345467")
+	assert.ErrorIs(t, err, accrual.ErrOrderNotRegistered)
+}
+
+func TestStub_DefaultScenario(t *testing.T) {
+	stub := New(Config{Default: &Scenario{Progression: []string{StatusProcessed}, Accrual: 10000}})
+	ts := httptest.NewServer(stub)
+	defer ts.Close()
+
+	res, err := accrual.NewClient(ts.URL).CheckOrder(context.Background(), "4561261212345467")
+	require.NoError(t, err)
+	assert.Equal(t, StatusProcessed, res.Status)
+	assert.Equal(t, money.Amount(10000), res.Accrual)
+}
+
+func TestStub_SubSecondRetryAfter(t *testing.T) {
+	stub := New(Config{RateLimit: 1, RetryAfter: 300 * time.Millisecond})
+	ts := httptest.NewServer(stub)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/orders/12345678903")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + "/api/orders/12345678903")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	// меньше секунды округляется вверх, а не до нуля
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+}
+
+func TestStub_RateLimit(t *testing.T) {
+	stub := New(Config{
+		RateLimit: 1,
+		Default:   &Scenario{Progression: []string{StatusProcessing, StatusProcessed}},
+	})
+	ts := httptest.NewServer(stub)
+	defer ts.Close()
+	client := accrual.NewClient(ts.URL)
+
+	res, err := client.CheckOrder(context.Background(), "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, StatusProcessing, res.Status)
+
+	_, err = client.CheckOrder(context.Background(), "12345678903")
+	var tooManyErr *accrual.TooManyRequestsError
+	require.True(t, errors.As(err, &tooManyErr))
+	assert.Equal(t, 1, tooManyErr.Limit)
+	assert.Greater(t, tooManyErr.RetryAfter, time.Duration(0))
+	assert.LessOrEqual(t, tooManyErr.RetryAfter, time.Minute)
+
+	// после сброса окно начинается заново, а 429 не сдвинул заказ по статусам
+	stub.Reset()
+	res, err = accrual.NewClient(ts.URL).CheckOrder(context.Background(), "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, StatusProcessing, res.Status)
+}
+
+func TestStub_Latency(t *testing.T) {
+	ts := httptest.NewServer(New(Config{Latency: time.Second}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := accrual.NewClient(ts.URL).CheckOrder(ctx, "12345678903")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestStub_PutScenario(t *testing.T) {
+	stub := New(Config{})
+	ts := httptest.NewServer(stub)
+	defer ts.Close()
+
+	put := func(body string) int {
+		req, _ := http.NewRequest(http.MethodPut, ts.URL+"/stub/orders/12345678903", strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusBadRequest, put(`{"progression": ["DONE"]}`))
+	assert.Equal(t, http.StatusNoContent, put(`{"progression": ["PROCESSED"], "accrual": 5, "latency": "1ms"}`))
+
+	res, err := accrual.NewClient(ts.URL).CheckOrder(context.Background(), "12345678903")
+	require.NoError(t, err)
+	assert.Equal(t, money.Amount(500), res.Accrual)
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenarios.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+rate_limit: 120
+latency: 20ms
+orders:
+  "12345678903":
+    progression: [PROCESSING, PROCESSED]
+    accrual: 729.98
+`), 0o600))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, 120, config.RateLimit)
+	assert.Equal(t, 20*time.Millisecond, config.Latency)
+	assert.Equal(t, money.Amount(72998), config.Orders["12345678903"].Accrual)
+
+	require.NoError(t, os.WriteFile(path, []byte("orders:\n  \"1\":\n    progression: [DONE]\n"), 0o600))
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, `orders.1: unknown status "DONE"`)
+}
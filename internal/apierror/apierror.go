@@ -0,0 +1,90 @@
+// Package apierror описывает ошибки API со стабильными кодами и отдаёт их клиенту
+// в формате application/problem+json (RFC 7807).
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/arseniy96/bonus-program/internal/services/validations"
+	"github.com/arseniy96/bonus-program/internal/store"
+)
+
+// Code — стабильный машиночитаемый код ошибки. Клиенты опираются на него, поэтому существующие коды не меняются.
+type Code string
+
+const (
+	CodeBadRequest               Code = "bad_request"
+	CodeValidationFailed         Code = "validation_failed"
+	CodeRequestTooLarge          Code = "request_too_large"
+	CodeUnauthorized             Code = "unauthorized"
+	CodeInvalidCredentials       Code = "invalid_credentials"
+	CodeInvalidRefreshToken      Code = "invalid_refresh_token"
+	CodeInsufficientFunds        Code = "insufficient_funds"
+	CodeNotFound                 Code = "not_found"
+	CodeConflict                 Code = "conflict"
+	CodeLoginTaken               Code = "login_taken"
+	CodeOrderOwnedByAnotherUser  Code = "order_owned_by_another_user"
+	CodeIdempotencyKeyInProgress Code = "idempotency_key_in_progress"
+	CodeIdempotencyKeyMismatch   Code = "idempotency_key_mismatch"
+	CodeInvalidOrderNumber       Code = "invalid_order_number"
+	CodeTooManyLoginAttempts     Code = "too_many_login_attempts"
+	CodeInternal                 Code = "internal_error"
+)
+
+// Error — ошибка, которую нужно показать клиенту: HTTP-статус, код и пояснение.
+// Причина (Err) клиенту не отдаётся, она нужна только для логов.
+type Error struct {
+	Status int
+	Code   Code
+	Detail string
+	Err    error
+}
+
+func New(status int, code Code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// Wrap сохраняет исходную ошибку как причину.
+func Wrap(err error, status int, code Code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return string(e.Code) + ": " + e.Detail + ": " + e.Err.Error()
+	}
+	return string(e.Code) + ": " + e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+var ErrUnauthorized = New(http.StatusUnauthorized, CodeUnauthorized, "authentication required")
+
+// From приводит произвольную ошибку к *Error. Доменные ошибки хранилища и проверок получают свои коды,
+// всё остальное считается внутренней ошибкой, подробности которой клиенту не показываются.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	switch {
+	case errors.Is(err, store.ErrConflict):
+		return Wrap(err, http.StatusConflict, CodeConflict, "resource already exists")
+	case errors.Is(err, store.ErrNowRows):
+		return Wrap(err, http.StatusNotFound, CodeNotFound, "resource not found")
+	case errors.Is(err, store.ErrInsufficientFunds):
+		return Wrap(err, http.StatusPaymentRequired, CodeInsufficientFunds, "not enough bonuses on balance")
+	case errors.Is(err, validations.ErrInvalidOrderNumber):
+		return Wrap(err, http.StatusUnprocessableEntity, CodeInvalidOrderNumber, "order number fails the Luhn check")
+	}
+	var fieldErrs validations.Errors
+	if errors.As(err, &fieldErrs) {
+		return Wrap(err, http.StatusBadRequest, CodeValidationFailed, "request validation failed")
+	}
+
+	return Wrap(err, http.StatusInternalServerError, CodeInternal, "internal server error")
+}
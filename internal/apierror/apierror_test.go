@@ -0,0 +1,106 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/arseniy96/bonus-program/internal/services/validations"
+	"github.com/arseniy96/bonus-program/internal/store"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   Code
+	}{
+		{name: "api error", err: New(http.StatusConflict, CodeLoginTaken, "login is already taken"), status: http.StatusConflict, code: CodeLoginTaken},
+		{name: "wrapped api error", err: fmt.Errorf("handler: %w", ErrUnauthorized), status: http.StatusUnauthorized, code: CodeUnauthorized},
+		{name: "conflict", err: store.ErrConflict, status: http.StatusConflict, code: CodeConflict},
+		{name: "not found", err: fmt.Errorf("find: %w", store.ErrNowRows), status: http.StatusNotFound, code: CodeNotFound},
+		{name: "insufficient funds", err: store.ErrInsufficientFunds, status: http.StatusPaymentRequired, code: CodeInsufficientFunds},
+		{name: "invalid luhn", err: validations.LuhnValidate("12345"), status: http.StatusUnprocessableEntity, code: CodeInvalidOrderNumber},
+		{name: "validation", err: validations.Errors{{Field: "login", Message: "must not be empty"}}, status: http.StatusBadRequest, code: CodeValidationFailed},
+		{name: "unknown", err: errors.New("connection refused"), status: http.StatusInternalServerError, code: CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := From(tt.err)
+			assert.Equal(t, tt.status, got.Status)
+			assert.Equal(t, tt.code, got.Code)
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     gin.HandlerFunc
+		statusCode  int
+		contentType string
+		response    string
+	}{
+		{
+			name: "domain error",
+			handler: func(c *gin.Context) {
+				Abort(c, store.ErrInsufficientFunds)
+			},
+			statusCode:  http.StatusPaymentRequired,
+			contentType: ContentType,
+			response:    `{"type":"about:blank","title":"Payment Required","status":402,"detail":"not enough bonuses on balance","instance":"/test","code":"insufficient_funds"}`,
+		},
+		{
+			name: "internal error details are hidden",
+			handler: func(c *gin.Context) {
+				Abort(c, errors.New("pq: password authentication failed"))
+			},
+			statusCode:  http.StatusInternalServerError,
+			contentType: ContentType,
+			response:    `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","instance":"/test","code":"internal_error"}`,
+		},
+		{
+			name: "field errors",
+			handler: func(c *gin.Context) {
+				Abort(c, validations.Errors{{Field: "sum", Message: "must be positive"}})
+			},
+			statusCode:  http.StatusBadRequest,
+			contentType: ContentType,
+			response:    `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request validation failed","instance":"/test","code":"validation_failed","errors":[{"field":"sum","message":"must be positive"}]}`,
+		},
+		{
+			name: "written response is kept",
+			handler: func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{})
+				_ = c.Error(errors.New("late error"))
+			},
+			statusCode:  http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			response:    `{}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Next()
+				Render(c)
+			})
+			r.GET("/test", tt.handler)
+
+			req, _ := http.NewRequest("GET", "/test", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Equal(t, tt.contentType, w.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.response, w.Body.String())
+		})
+	}
+}
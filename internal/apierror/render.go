@@ -0,0 +1,54 @@
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/arseniy96/bonus-program/internal/services/validations"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem — тело ответа об ошибке по RFC 7807, дополненное кодом ошибки и ошибками по полям.
+type Problem struct {
+	Type     string             `json:"type"`
+	Title    string             `json:"title"`
+	Status   int                `json:"status"`
+	Detail   string             `json:"detail,omitempty"`
+	Instance string             `json:"instance,omitempty"`
+	Code     Code               `json:"code"`
+	Errors   validations.Errors `json:"errors,omitempty"`
+}
+
+// Abort прерывает обработку запроса с ошибкой. Ответ формирует Render.
+func Abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// Render отдаёт клиенту последнюю ошибку запроса, если ответ ещё не был записан.
+func Render(c *gin.Context) {
+	if c.Writer.Written() || len(c.Errors) == 0 {
+		return
+	}
+
+	err := c.Errors.Last().Err
+	apiErr := From(err)
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(apiErr.Status),
+		Status:   apiErr.Status,
+		Detail:   apiErr.Detail,
+		Instance: c.Request.URL.Path,
+		Code:     apiErr.Code,
+	}
+	var fieldErrs validations.Errors
+	if errors.As(err, &fieldErrs) {
+		problem.Errors = fieldErrs
+	}
+
+	c.Header("Content-Type", ContentType)
+	c.JSON(apiErr.Status, problem)
+}
@@ -2,30 +2,208 @@ package config
 
 import (
 	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/caarlos0/env"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// EnvConfigFile — переменная окружения с путём к файлу конфигурации, если он не передан флагом -config
+const EnvConfigFile = "CONFIG_FILE"
+
 type Settings struct {
-	Host         string `env:"RUN_ADDRESS"`
-	DatabaseURI  string `env:"DATABASE_URI"`
-	AccrualHost  string `env:"ACCRUAL_SYSTEM_ADDRESS"`
-	LoggingLevel string `env:"LOG_LEVEL"`
+	Host         string `env:"RUN_ADDRESS" yaml:"run_address"`
+	DatabaseURI  string `env:"DATABASE_URI" yaml:"database_uri"`
+	AccrualHost  string `env:"ACCRUAL_SYSTEM_ADDRESS" yaml:"accrual_system_address"`
+	LoggingLevel string `env:"LOG_LEVEL" yaml:"log_level"`
+	WorkersCount int    `env:"ACCRUAL_WORKERS" yaml:"accrual_workers"`
+	// MetricsAddress — адрес отдельного внутреннего сервера с /metrics, чтобы метрики не были доступны
+	// на публичном адресе run_address; пустой адрес отключает метрики
+	MetricsAddress string `env:"METRICS_ADDRESS" yaml:"metrics_address"`
+	// AccrualProvider — откуда брать начисления: http (внешняя система по AccrualHost), rules (расчёт
+	// в процессе по правилам из базы) или fixture (фиксированные ответы из AccrualFixtureFile)
+	AccrualProvider    string `env:"ACCRUAL_PROVIDER" yaml:"accrual_provider"`
+	AccrualFixtureFile string `env:"ACCRUAL_FIXTURE_FILE" yaml:"accrual_fixture_file"`
+	// AccrualBreakerFailures — после скольких неудачных запросов подряд circuit breaker перестаёт обращаться
+	// к системе начислений на AccrualBreakerCoolDown; 0 отключает breaker. После паузы пропускается
+	// AccrualBreakerHalfOpenRequests пробных запросов, и если все они успешны, breaker замыкается.
+	AccrualBreakerFailures         int           `env:"ACCRUAL_BREAKER_FAILURES" yaml:"accrual_breaker_failures"`
+	AccrualBreakerCoolDown         time.Duration `env:"ACCRUAL_BREAKER_COOL_DOWN" yaml:"accrual_breaker_cool_down"`
+	AccrualBreakerHalfOpenRequests int           `env:"ACCRUAL_BREAKER_HALF_OPEN_REQUESTS" yaml:"accrual_breaker_half_open_requests"`
+	// OrderMaxAttempts и OrderMaxAge — после скольких неудачных попыток опроса или через сколько после постановки
+	// в очередь заказ без финального статуса уходит в dead letter; 0 отключает ограничение. Неудачной считается
+	// попытка, завершившаяся ошибкой или ответом 204; ответы REGISTERED и PROCESSING попыток не тратят
+	// и ограничены только OrderMaxAge.
+	// Возраст считается от order_jobs.enqueued_at: он сбрасывается при возврате заказа из dead letter,
+	// а задачам, стоявшим в очереди до миграции 00018, отсчитывается с момента этой миграции.
+	OrderMaxAttempts int           `env:"ORDER_MAX_ATTEMPTS" yaml:"order_max_attempts"`
+	OrderMaxAge      time.Duration `env:"ORDER_MAX_AGE" yaml:"order_max_age"`
+	// AdminToken — токен для /api/admin; если не задан, административные методы отключены
+	AdminToken string `env:"ADMIN_TOKEN" yaml:"admin_token"`
+	// ShutdownTimeout — сколько ждём завершения запросов и фоновых обработчиков при остановке
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" yaml:"shutdown_timeout"`
+	// PasswordHashCost — стоимость bcrypt; при её изменении пароли перехэшируются при следующем входе
+	PasswordHashCost int `env:"PASSWORD_HASH_COST" yaml:"password_hash_cost"`
+	// PasswordMinLength и PasswordMinClasses — требования к паролю при регистрации; классы символов —
+	// строчные и заглавные буквы, цифры и прочие символы
+	PasswordMinLength  int `env:"PASSWORD_MIN_LENGTH" yaml:"password_min_length"`
+	PasswordMinClasses int `env:"PASSWORD_MIN_CLASSES" yaml:"password_min_classes"`
+	// JWTSigningKeys — ключи подписи токенов в формате «kid1:secret1,kid2:secret2»
+	JWTSigningKeys string `env:"JWT_SIGNING_KEYS" yaml:"jwt_signing_keys"`
+	// JWTActiveKeyID — каким ключом подписывать новые токены, по умолчанию первым
+	JWTActiveKeyID string `env:"JWT_ACTIVE_KEY_ID" yaml:"jwt_active_key_id"`
+	// AccessTokenTTL — время жизни JWT; отозванная сессия продолжает работать не дольше этого срока
+	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL" yaml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL" yaml:"refresh_token_ttl"`
+	// ThrottleStore — где хранить счётчики неудачных входов: postgres (общие для реплик) или memory
+	ThrottleStore string `env:"THROTTLE_STORE" yaml:"throttle_store"`
+	// LoginMaxFailures и LoginMaxFailuresPerIP — сколько неудачных входов в окне LoginFailureWindow
+	// допускается для одного логина и одного IP до блокировки; 0 отключает ограничение
+	LoginMaxFailures      int           `env:"LOGIN_MAX_FAILURES" yaml:"login_max_failures"`
+	LoginMaxFailuresPerIP int           `env:"LOGIN_MAX_FAILURES_PER_IP" yaml:"login_max_failures_per_ip"`
+	LoginFailureWindow    time.Duration `env:"LOGIN_FAILURE_WINDOW" yaml:"login_failure_window"`
+	// LoginLockout — первая блокировка, каждая следующая вдвое дольше, но не больше LoginMaxLockout
+	LoginLockout    time.Duration `env:"LOGIN_LOCKOUT" yaml:"login_lockout"`
+	LoginMaxLockout time.Duration `env:"LOGIN_MAX_LOCKOUT" yaml:"login_max_lockout"`
+	// TrustedProxies — адреса и подсети обратных прокси, которым доверяем заголовок X-Forwarded-For.
+	// По умолчанию список пуст, и IP клиента берётся из адреса соединения: иначе клиент мог бы подставлять
+	// любой адрес в заголовок и обходить ограничение неудачных входов по IP.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" yaml:"trusted_proxies"`
+	// TracingEndpoint — адрес OTLP/HTTP коллектора (host:port); если не задан, трассы никуда не отправляются
+	TracingEndpoint    string  `env:"TRACING_OTLP_ENDPOINT" yaml:"tracing_otlp_endpoint"`
+	TracingInsecure    bool    `env:"TRACING_OTLP_INSECURE" yaml:"tracing_otlp_insecure"`
+	TracingSampleRatio float64 `env:"TRACING_SAMPLE_RATIO" yaml:"tracing_sample_ratio"`
+
+	// DatabaseURIFile, JWTSigningKeysFile и AdminTokenFile — пути к файлам с секретами (например,
+	// Docker/Kubernetes secrets). Если путь задан, значение из файла заменяет DatabaseURI, JWTSigningKeys или AdminToken,
+	// кроме случая, когда само значение задано в более приоритетном источнике.
+	DatabaseURIFile    string `env:"DATABASE_URI_FILE" yaml:"database_uri_file"`
+	JWTSigningKeysFile string `env:"JWT_SIGNING_KEYS_FILE" yaml:"jwt_signing_keys_file"`
+	AdminTokenFile     string `env:"ADMIN_TOKEN_FILE" yaml:"admin_token_file"`
+
+	// ConfigFile — YAML или JSON файл с настройками
+	ConfigFile string `yaml:"-"`
+	// PrintConfig — вывести итоговые настройки без секретов и завершиться
+	PrintConfig bool `yaml:"-"`
 }
 
-func Initialize() *Settings {
+// Initialize собирает настройки из аргументов командной строки, окружения и файла конфигурации и проверяет их.
+// В режиме -print-config проверка пропускается, чтобы можно было посмотреть и на некорректные настройки.
+func Initialize() (*Settings, error) {
+	settings, err := Load(os.Args[1:])
+	if err != nil {
+		return nil, err
+	}
+	if settings.PrintConfig {
+		return settings, nil
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Load собирает настройки в порядке приоритета: значения по умолчанию < файл конфигурации < переменные
+// окружения < флаги.
+func Load(args []string) (*Settings, error) {
 	settings := &Settings{}
+	// флаги записывают значения по умолчанию при регистрации
+	fs := newFlagSet(settings)
 
-	flag.StringVar(&settings.Host, "a", "localhost:8080", "server host with port")
-	flag.StringVar(&settings.DatabaseURI, "d", "", "database connection data")
-	flag.StringVar(&settings.AccrualHost, "r", "localhost:8080", "accrual host")
-	flag.StringVar(&settings.LoggingLevel, "l", "info", "log level")
-	flag.Parse()
+	// первый разбор нужен, чтобы узнать путь к файлу конфигурации
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	configFile := settings.ConfigFile
+	if !isFlagSet(fs, "config") {
+		configFile = os.Getenv(EnvConfigFile)
+	}
+	if configFile != "" {
+		if err := loadFile(configFile, settings); err != nil {
+			return nil, err
+		}
+	}
+	settings.ConfigFile = configFile
+	fromFile := *settings
 
-	err := env.Parse(settings)
-	if err != nil {
-		panic(err)
+	if err := env.Parse(settings); err != nil {
+		return nil, fmt.Errorf("parse environment: %w", err)
 	}
+	// флаги применяются повторно поверх файла и окружения
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := settings.readSecretFiles(fs, &fromFile); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+func newFlagSet(settings *Settings) *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+	fs.StringVar(&settings.ConfigFile, "config", "", "path to YAML or JSON config file")
+	fs.BoolVar(&settings.PrintConfig, "print-config", false, "print effective configuration with secrets redacted and exit")
+	fs.StringVar(&settings.Host, "a", "localhost:8080", "server host with port")
+	fs.StringVar(&settings.MetricsAddress, "metrics-address", "localhost:9090", "internal metrics server host with port, metrics are disabled if empty")
+	fs.StringVar(&settings.DatabaseURI, "d", "", "database connection data")
+	fs.StringVar(&settings.DatabaseURIFile, "d-file", "", "file with database connection data")
+	fs.StringVar(&settings.AccrualHost, "r", "http://localhost:8081", "accrual system address")
+	fs.StringVar(&settings.AccrualProvider, "accrual-provider", AccrualProviderHTTP, "accrual provider: http, rules or fixture")
+	fs.StringVar(&settings.AccrualFixtureFile, "accrual-fixture", "", "YAML or JSON file with accrual results for fixture provider")
+	fs.IntVar(&settings.AccrualBreakerFailures, "accrual-breaker-failures", 5, "consecutive accrual failures that open the circuit breaker, 0 disables it")
+	fs.DurationVar(&settings.AccrualBreakerCoolDown, "accrual-breaker-cool-down", 30*time.Second, "how long the open circuit breaker rejects accrual requests")
+	fs.IntVar(&settings.AccrualBreakerHalfOpenRequests, "accrual-breaker-half-open", 1, "trial accrual requests after cool-down")
+	fs.StringVar(&settings.LoggingLevel, "l", "info", "log level")
+	fs.IntVar(&settings.WorkersCount, "w", 4, "number of accrual workers")
+	fs.IntVar(&settings.OrderMaxAttempts, "order-max-attempts", 20, "failed or unregistered accrual checks before an order goes to dead letter, 0 means unlimited")
+	fs.DurationVar(&settings.OrderMaxAge, "order-max-age", 72*time.Hour, "how long an order is checked before it goes to dead letter, 0 means unlimited")
+	fs.StringVar(&settings.AdminToken, "admin-token", "", "token for admin API, admin API is disabled if empty")
+	fs.StringVar(&settings.AdminTokenFile, "admin-token-file", "", "file with token for admin API")
+	fs.DurationVar(&settings.ShutdownTimeout, "s", 10*time.Second, "graceful shutdown timeout")
+	fs.IntVar(&settings.PasswordHashCost, "c", bcrypt.DefaultCost, "bcrypt password hash cost")
+	fs.IntVar(&settings.PasswordMinLength, "password-min-length", 8, "minimal password length")
+	fs.IntVar(&settings.PasswordMinClasses, "password-min-classes", 1, "minimal number of character classes in password")
+	fs.DurationVar(&settings.AccessTokenTTL, "access-ttl", 15*time.Minute, "access token ttl")
+	fs.DurationVar(&settings.RefreshTokenTTL, "refresh-ttl", 30*24*time.Hour, "refresh token ttl")
+	fs.StringVar(&settings.ThrottleStore, "throttle-store", ThrottleStorePostgres, "login throttling store: postgres or memory")
+	fs.IntVar(&settings.LoginMaxFailures, "login-max-failures", 5, "failed logins per login before lockout")
+	fs.IntVar(&settings.LoginMaxFailuresPerIP, "login-max-failures-ip", 50, "failed logins per client ip before lockout")
+	fs.DurationVar(&settings.LoginFailureWindow, "login-failure-window", 15*time.Minute, "failed logins counting window")
+	fs.DurationVar(&settings.LoginLockout, "login-lockout", time.Minute, "first login lockout duration")
+	fs.DurationVar(&settings.LoginMaxLockout, "login-max-lockout", time.Hour, "max login lockout duration")
+	fs.Func("trusted-proxies", "comma-separated trusted reverse proxy ips or cidrs", func(value string) error {
+		settings.TrustedProxies = splitList(value)
+		return nil
+	})
+	fs.StringVar(&settings.TracingEndpoint, "otlp-endpoint", "", "OTLP/HTTP trace collector host:port")
+	fs.BoolVar(&settings.TracingInsecure, "otlp-insecure", false, "send traces over plain HTTP")
+	fs.Float64Var(&settings.TracingSampleRatio, "trace-sample-ratio", 1, "share of traces to sample")
+
+	return fs
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
 
-	return settings
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clearEnv убирает из окружения все переменные, которые читает Load, чтобы на тесты не влияли
+// настройки машины, где они запускаются. Исходные значения восстанавливаются после теста.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	names := []string{EnvConfigFile}
+	settings := reflect.TypeOf(Settings{})
+	for i := 0; i < settings.NumField(); i++ {
+		if name, ok := settings.Field(i).Tag.Lookup("env"); ok {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		t.Setenv(name, "")
+		require.NoError(t, os.Unsetenv(name))
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+run_address: file:8080
+log_level: warn
+accrual_workers: 8
+shutdown_timeout: 30s
+`)
+	t.Setenv(EnvConfigFile, path)
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("ACCRUAL_WORKERS", "16")
+
+	settings, err := Load([]string{"-w", "2"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "file:8080", settings.Host)
+	assert.Equal(t, "debug", settings.LoggingLevel)
+	assert.Equal(t, 2, settings.WorkersCount)
+	assert.Equal(t, 30*time.Second, settings.ShutdownTimeout)
+	// не заданные нигде параметры берутся по умолчанию
+	assert.Equal(t, 15*time.Minute, settings.AccessTokenTTL)
+	assert.Equal(t, path, settings.ConfigFile)
+}
+
+func TestLoad_ConfigFlagOverridesEnv(t *testing.T) {
+	clearEnv(t)
+	t.Setenv(EnvConfigFile, filepath.Join(t.TempDir(), "missing.yaml"))
+	path := writeFile(t, "config.json", `{"run_address": "json:8080", "login_lockout": "2m"}`)
+
+	settings, err := Load([]string{"-config", path})
+	require.NoError(t, err)
+	assert.Equal(t, "json:8080", settings.Host)
+	assert.Equal(t, 2*time.Minute, settings.LoginLockout)
+}
+
+func TestLoad_UnknownKey(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", "run_adress: localhost:8080\n")
+
+	_, err := Load([]string{"-config", path})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "run_adress")
+}
+
+func TestLoad_SecretFiles(t *testing.T) {
+	clearEnv(t)
+	dsn := writeFile(t, "dsn", "postgres://user:secret@db:5432/gophermart\n")
+	t.Setenv("DATABASE_URI_FILE", dsn)
+
+	settings, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://user:secret@db:5432/gophermart", settings.DatabaseURI)
+
+	// флаг приоритетнее окружения, файл с секретом не читается
+	settings, err = Load([]string{"-d", "postgres://localhost/gophermart"})
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://localhost/gophermart", settings.DatabaseURI)
+
+	// в одном источнике значение и путь к файлу взаимоисключающие
+	t.Setenv("DATABASE_URI", "postgres://localhost/gophermart")
+	_, err = Load(nil)
+	assert.ErrorContains(t, err, "database_uri and database_uri_file are mutually exclusive")
+}
+
+func TestLoad_SecretFilesPrecedence(t *testing.T) {
+	clearEnv(t)
+	token := writeFile(t, "token", "admin-token-from-file\n")
+	path := writeFile(t, "config.yaml", "admin_token: admin-token-from-config\n")
+	t.Setenv("ADMIN_TOKEN_FILE", token)
+
+	// путь из окружения приоритетнее значения из файла конфигурации
+	settings, err := Load([]string{"-config", path})
+	require.NoError(t, err)
+	assert.Equal(t, "admin-token-from-file", settings.AdminToken)
+
+	path = writeFile(t, "config.yaml", "admin_token: admin-token-from-config\nadmin_token_file: "+token+"\n")
+	clearEnv(t)
+	_, err = Load([]string{"-config", path})
+	assert.ErrorContains(t, err, "admin_token and admin_token_file are mutually exclusive")
+
+	// значение из окружения приоритетнее пути из файла конфигурации
+	t.Setenv("ADMIN_TOKEN", "admin-token-from-env")
+	settings, err = Load([]string{"-config", path})
+	require.NoError(t, err)
+	assert.Equal(t, "admin-token-from-env", settings.AdminToken)
+}
+
+func TestSettings_Validate(t *testing.T) {
+	clearEnv(t)
+	settings, err := Load([]string{"-d", "postgres://localhost/gophermart"})
+	require.NoError(t, err)
+	require.NoError(t, settings.Validate())
+
+	settings.MetricsAddress = settings.Host
+	settings.DatabaseURI = ""
+	settings.AccrualHost = "localhost:8080"
+	settings.WorkersCount = 0
+	settings.RefreshTokenTTL = time.Minute
+	settings.ThrottleStore = "redis"
+
+	err = settings.Validate()
+	var validationErr ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, ValidationError{
+		"metrics_address: must differ from run_address",
+		"database_uri: is required",
+		`accrual_system_address: must be an http(s) URL, got "localhost:8080"`,
+		"accrual_workers: must be positive, got 0",
+		"refresh_token_ttl: must be longer than access_token_ttl (15m0s), got 1m0s",
+		`throttle_store: must be "postgres" or "memory", got "redis"`,
+	}, validationErr)
+}
+
+func TestSettings_Print(t *testing.T) {
+	clearEnv(t)
+	settings, err := Load([]string{"-d", "postgres://user:secret@db:5432/gophermart"})
+	require.NoError(t, err)
+	settings.JWTSigningKeys = "2024:first-secret,2025:second-secret"
+
+	var buf bytes.Buffer
+	require.NoError(t, settings.Print(&buf))
+
+	out := buf.String()
+	assert.NotContains(t, out, "secret@")
+	assert.NotContains(t, out, "first-secret")
+	assert.Contains(t, out, "database_uri: postgres://user:xxxxx@db:5432/gophermart")
+	assert.Contains(t, out, "jwt_signing_keys: 2024:[REDACTED],2025:[REDACTED]")
+	assert.Contains(t, out, "access_token_ttl: 15m0s")
+	// исходные настройки не меняются
+	assert.Equal(t, "postgres://user:secret@db:5432/gophermart", settings.DatabaseURI)
+
+	assert.Equal(t, redacted, redactDSN("host=db password=secret"))
+}
+
+func TestSettings_ValidateAccrualProvider(t *testing.T) {
+	clearEnv(t)
+	settings, err := Load([]string{"-d", "postgres://localhost/gophermart", "-accrual-provider", "fixture", "-r", ""})
+	require.NoError(t, err)
+	assert.Equal(t, ValidationError{"accrual_fixture_file: is required for fixture provider"}, settings.Validate())
+
+	settings.AccrualFixtureFile = "fixture.yaml"
+	assert.NoError(t, settings.Validate())
+
+	settings.AccrualProvider = "grpc"
+	assert.ErrorContains(t, settings.Validate(), `accrual_provider: must be "http", "rules" or "fixture", got "grpc"`)
+}
+
+func TestSettings_ValidateAccrualBreaker(t *testing.T) {
+	clearEnv(t)
+	settings, err := Load([]string{"-d", "postgres://localhost/gophermart", "-accrual-breaker-cool-down", "0s"})
+	require.NoError(t, err)
+	assert.Equal(t, ValidationError{"accrual_breaker_cool_down: must be positive, got 0s"}, settings.Validate())
+
+	// с выключенным breaker его параметры не проверяются
+	settings.AccrualBreakerFailures = 0
+	assert.NoError(t, settings.Validate())
+
+	settings.AccrualBreakerFailures = -1
+	assert.Equal(t, ValidationError{"accrual_breaker_failures: must not be negative, got -1"}, settings.Validate())
+}
+
+func TestSettings_ValidateDeadLetter(t *testing.T) {
+	clearEnv(t)
+	settings, err := Load([]string{"-d", "postgres://localhost/gophermart", "-order-max-attempts", "-1", "-admin-token", "short"})
+	require.NoError(t, err)
+	assert.Equal(t, ValidationError{
+		"order_max_attempts: must not be negative, got -1",
+		"admin_token: must be at least 16 characters",
+	}, settings.Validate())
+
+	settings.OrderMaxAttempts = 0
+	settings.AdminToken = "admin-token-admin-token"
+	assert.NoError(t, settings.Validate())
+	assert.Equal(t, redacted, settings.Redacted().AdminToken)
+}
+
+func TestSettings_TrustedProxies(t *testing.T) {
+	clearEnv(t)
+	settings, err := Load([]string{"-d", "postgres://localhost/gophermart"})
+	require.NoError(t, err)
+	// по умолчанию X-Forwarded-For не доверяем никому
+	assert.Empty(t, settings.TrustedProxies)
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.1,192.168.0.0/16")
+	settings, err = Load([]string{"-d", "postgres://localhost/gophermart"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "192.168.0.0/16"}, settings.TrustedProxies)
+	assert.NoError(t, settings.Validate())
+
+	settings, err = Load([]string{"-d", "postgres://localhost/gophermart", "-trusted-proxies", "127.0.0.1, proxy"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"127.0.0.1", "proxy"}, settings.TrustedProxies)
+	assert.Equal(t, ValidationError{`trusted_proxies: must be an ip or cidr, got "proxy"`}, settings.Validate())
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadFile читает настройки из YAML-файла. JSON — подмножество YAML, поэтому так же читаются и JSON-файлы.
+// Неизвестные ключи считаются ошибкой, чтобы опечатка в имени параметра не проходила незамеченной.
+func loadFile(path string, settings *Settings) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(settings); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// source — откуда взято значение настройки, в порядке возрастания приоритета.
+type source int
+
+const (
+	sourceNone source = iota
+	sourceFile
+	sourceEnv
+	sourceFlag
+)
+
+// sourceOf определяет, откуда взято значение: fileValue — значение сразу после чтения файла конфигурации,
+// пустые envName или flagName означают, что настройку нельзя задать этим способом.
+func sourceOf(fs *flag.FlagSet, fileValue, envName, flagName string) source {
+	switch {
+	case flagName != "" && isFlagSet(fs, flagName):
+		return sourceFlag
+	case envName != "" && os.Getenv(envName) != "":
+		return sourceEnv
+	case fileValue != "":
+		return sourceFile
+	}
+	return sourceNone
+}
+
+// readSecretFiles подставляет секреты из файлов, заданных в *_FILE настройках. Секрет и путь к нему
+// взаимоисключающие только в одном источнике; если они заданы в разных, побеждает более приоритетный,
+// как и для остальных настроек: например, -d перекрывает DATABASE_URI_FILE. fromFile — настройки сразу
+// после чтения файла конфигурации.
+func (s *Settings) readSecretFiles(fs *flag.FlagSet, fromFile *Settings) error {
+	secrets := []struct {
+		name        string
+		value       *string
+		valueSource source
+		path        string
+		pathSource  source
+	}{
+		{
+			name:        "database_uri",
+			value:       &s.DatabaseURI,
+			valueSource: sourceOf(fs, fromFile.DatabaseURI, "DATABASE_URI", "d"),
+			path:        s.DatabaseURIFile,
+			pathSource:  sourceOf(fs, fromFile.DatabaseURIFile, "DATABASE_URI_FILE", "d-file"),
+		},
+		{
+			name:        "jwt_signing_keys",
+			value:       &s.JWTSigningKeys,
+			valueSource: sourceOf(fs, fromFile.JWTSigningKeys, "JWT_SIGNING_KEYS", ""),
+			path:        s.JWTSigningKeysFile,
+			pathSource:  sourceOf(fs, fromFile.JWTSigningKeysFile, "JWT_SIGNING_KEYS_FILE", ""),
+		},
+		{
+			name:        "admin_token",
+			value:       &s.AdminToken,
+			valueSource: sourceOf(fs, fromFile.AdminToken, "ADMIN_TOKEN", "admin-token"),
+			path:        s.AdminTokenFile,
+			pathSource:  sourceOf(fs, fromFile.AdminTokenFile, "ADMIN_TOKEN_FILE", "admin-token-file"),
+		},
+	}
+	for _, secret := range secrets {
+		if secret.path == "" || secret.valueSource > secret.pathSource {
+			continue
+		}
+		if secret.valueSource == secret.pathSource && *secret.value != "" {
+			return fmt.Errorf("%s and %s_file are mutually exclusive", secret.name, secret.name)
+		}
+		data, err := os.ReadFile(secret.path)
+		if err != nil {
+			return fmt.Errorf("read %s_file: %w", secret.name, err)
+		}
+		*secret.value = strings.TrimSpace(string(data))
+	}
+	return nil
+}
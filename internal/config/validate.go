@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/arseniy96/bonus-program/internal/services/validations"
+)
+
+const (
+	ThrottleStorePostgres = "postgres"
+	ThrottleStoreMemory   = "memory"
+
+	AccrualProviderHTTP    = "http"
+	AccrualProviderRules   = "rules"
+	AccrualProviderFixture = "fixture"
+
+	// minAdminTokenLength — токен администратора должен быть не короче, чтобы его нельзя было подобрать
+	minAdminTokenLength = 16
+	passwordClasses     = 4
+)
+
+// ValidationError перечисляет все найденные в настройках ошибки, а не только первую.
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e, "\n  ")
+}
+
+// Validate проверяет настройки. Ошибки называют параметры так же, как в файле конфигурации.
+func (s *Settings) Validate() error {
+	var errs ValidationError
+	check := func(ok bool, field, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, field+": "+fmt.Sprintf(format, args...))
+		}
+	}
+
+	if _, _, err := net.SplitHostPort(s.Host); err != nil {
+		errs = append(errs, fmt.Sprintf("run_address: must be host:port, got %q", s.Host))
+	}
+	if s.MetricsAddress != "" {
+		if _, _, err := net.SplitHostPort(s.MetricsAddress); err != nil {
+			errs = append(errs, fmt.Sprintf("metrics_address: must be host:port, got %q", s.MetricsAddress))
+		}
+		check(s.MetricsAddress != s.Host, "metrics_address", "must differ from run_address")
+	}
+	check(s.DatabaseURI != "", "database_uri", "is required")
+	switch s.AccrualProvider {
+	case AccrualProviderHTTP:
+		if u, err := url.Parse(s.AccrualHost); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Sprintf("accrual_system_address: must be an http(s) URL, got %q", s.AccrualHost))
+		}
+	case AccrualProviderRules:
+	case AccrualProviderFixture:
+		check(s.AccrualFixtureFile != "", "accrual_fixture_file", "is required for %s provider", AccrualProviderFixture)
+	default:
+		errs = append(errs, fmt.Sprintf("accrual_provider: must be %q, %q or %q, got %q",
+			AccrualProviderHTTP, AccrualProviderRules, AccrualProviderFixture, s.AccrualProvider))
+	}
+	check(s.AccrualBreakerFailures >= 0,
+		"accrual_breaker_failures", "must not be negative, got %d", s.AccrualBreakerFailures)
+	if s.AccrualBreakerFailures > 0 {
+		check(s.AccrualBreakerCoolDown > 0,
+			"accrual_breaker_cool_down", "must be positive, got %v", s.AccrualBreakerCoolDown)
+		check(s.AccrualBreakerHalfOpenRequests > 0,
+			"accrual_breaker_half_open_requests", "must be positive, got %d", s.AccrualBreakerHalfOpenRequests)
+	}
+	if _, err := zap.ParseAtomicLevel(s.LoggingLevel); err != nil {
+		errs = append(errs, fmt.Sprintf("log_level: unknown level %q", s.LoggingLevel))
+	}
+	check(s.WorkersCount > 0, "accrual_workers", "must be positive, got %d", s.WorkersCount)
+	check(s.OrderMaxAttempts >= 0, "order_max_attempts", "must not be negative, got %d", s.OrderMaxAttempts)
+	check(s.OrderMaxAge >= 0, "order_max_age", "must not be negative, got %v", s.OrderMaxAge)
+	check(s.ShutdownTimeout > 0, "shutdown_timeout", "must be positive, got %v", s.ShutdownTimeout)
+
+	check(s.PasswordHashCost >= bcrypt.MinCost && s.PasswordHashCost <= bcrypt.MaxCost,
+		"password_hash_cost", "must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, s.PasswordHashCost)
+	check(s.PasswordMinLength > 0 && s.PasswordMinLength <= validations.PasswordMaxBytes,
+		"password_min_length", "must be between 1 and %d, got %d", validations.PasswordMaxBytes, s.PasswordMinLength)
+	check(s.PasswordMinClasses >= 0 && s.PasswordMinClasses <= passwordClasses,
+		"password_min_classes", "must be between 0 and %d, got %d", passwordClasses, s.PasswordMinClasses)
+
+	check(s.JWTActiveKeyID == "" || s.JWTSigningKeys != "",
+		"jwt_active_key_id", "requires jwt_signing_keys")
+	check(s.AccessTokenTTL > 0, "access_token_ttl", "must be positive, got %v", s.AccessTokenTTL)
+	check(s.RefreshTokenTTL > s.AccessTokenTTL,
+		"refresh_token_ttl", "must be longer than access_token_ttl (%v), got %v", s.AccessTokenTTL, s.RefreshTokenTTL)
+
+	check(s.ThrottleStore == ThrottleStorePostgres || s.ThrottleStore == ThrottleStoreMemory,
+		"throttle_store", "must be %q or %q, got %q", ThrottleStorePostgres, ThrottleStoreMemory, s.ThrottleStore)
+	check(s.LoginMaxFailures >= 0, "login_max_failures", "must not be negative, got %d", s.LoginMaxFailures)
+	check(s.LoginMaxFailuresPerIP >= 0,
+		"login_max_failures_per_ip", "must not be negative, got %d", s.LoginMaxFailuresPerIP)
+	check(s.LoginFailureWindow > 0, "login_failure_window", "must be positive, got %v", s.LoginFailureWindow)
+	check(s.LoginLockout > 0, "login_lockout", "must be positive, got %v", s.LoginLockout)
+	check(s.LoginMaxLockout >= s.LoginLockout,
+		"login_max_lockout", "must not be shorter than login_lockout (%v), got %v", s.LoginLockout, s.LoginMaxLockout)
+
+	for _, proxy := range s.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Sprintf("trusted_proxies: must be an ip or cidr, got %q", proxy))
+			}
+		}
+	}
+
+	check(s.AdminToken == "" || len(s.AdminToken) >= minAdminTokenLength,
+		"admin_token", "must be at least %d characters", minAdminTokenLength)
+
+	check(s.TracingSampleRatio >= 0 && s.TracingSampleRatio <= 1,
+		"tracing_sample_ratio", "must be between 0 and 1, got %v", s.TracingSampleRatio)
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// RequestIDField — имя поля с ID запроса в логах.
+const RequestIDField = "request_id"
+
+type requestIDKey struct{}
+
+// WithRequestID сохраняет ID запроса в контексте, чтобы по нему можно было связать логи обработчика,
+// хранилища и фоновых задач, порождённых запросом.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// FromContext возвращает логгер, дополненный ID запроса из ctx, если он там есть.
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	if requestID := RequestID(ctx); requestID != "" {
+		return Log.With(RequestIDField, requestID)
+	}
+	return Log
+}
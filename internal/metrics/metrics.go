@@ -0,0 +1,115 @@
+// Package metrics собирает метрики сервиса в формате Prometheus и отдаёт их на /metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/arseniy96/bonus-program/internal/services/money"
+)
+
+const namespace = "gophermart"
+
+// Registry — реестр метрик сервиса. Отдельный от prometheus.DefaultRegisterer, чтобы в тестах
+// и при повторной инициализации не было конфликтов регистрации.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	OrdersProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "orders",
+		Name:      "processed_total",
+		Help:      "Orders that reached a final status.",
+	}, []string{"status"})
+
+	OrdersDeadLettered = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "orders",
+		Name:      "dead_lettered_total",
+		Help:      "Orders moved to dead letter after running out of accrual check attempts.",
+	})
+
+	AccrualRequestDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of requests to the accrual system.",
+		Buckets:   prometheus.DefBuckets,
+	})
+	AccrualRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "requests_total",
+		Help:      "Requests to the accrual system by result: ok, not_registered, too_many_requests, error.",
+	}, []string{"result"})
+
+	AccrualCircuitState = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "circuit_state",
+		Help:      "State of the accrual circuit breaker: 0 closed, 1 half-open, 2 open.",
+	})
+	AccrualCircuitTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "circuit_transitions_total",
+		Help:      "Accrual circuit breaker state changes by new state.",
+	}, []string{"state"})
+
+	BonusesAccrued = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "bonuses",
+		Name:      "accrued_total",
+		Help:      "Bonus points credited to users.",
+	})
+	BonusesWithdrawn = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "bonuses",
+		Name:      "withdrawn_total",
+		Help:      "Bonus points withdrawn by users.",
+	})
+)
+
+// Результаты запросов к системе начислений для AccrualRequests.
+const (
+	AccrualResultOK              = "ok"
+	AccrualResultNotRegistered   = "not_registered"
+	AccrualResultTooManyRequests = "too_many_requests"
+	AccrualResultError           = "error"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		OrdersProcessed,
+		OrdersDeadLettered,
+		AccrualRequestDuration,
+		AccrualRequests,
+		AccrualCircuitState,
+		AccrualCircuitTransitions,
+		BonusesAccrued,
+		BonusesWithdrawn,
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Points переводит сумму в баллы для счётчиков. Точность float64 здесь достаточна.
+func Points(a money.Amount) float64 {
+	return float64(a.Cents()) / money.Scale
+}
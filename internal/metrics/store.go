@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+
+	"github.com/arseniy96/bonus-program/internal/logger"
+)
+
+// queueStatsTimeout — сколько ждать запрос размера очереди при сборе метрик.
+const queueStatsTimeout = 2 * time.Second
+
+// QueueStatsFunc возвращает число задач в очереди заказов, число тех из них, время которых уже подошло,
+// и число задач в dead letter.
+type QueueStatsFunc func(ctx context.Context) (total, ready, deadLettered int, err error)
+
+type queueCollector struct {
+	stats        QueueStatsFunc
+	total        *prometheus.Desc
+	ready        *prometheus.Desc
+	deadLettered *prometheus.Desc
+}
+
+// RegisterStore добавляет в реестр статистику пула соединений и размер очереди заказов.
+// Размер очереди запрашивается из базы при каждом сборе метрик.
+func RegisterStore(db *sql.DB, stats QueueStatsFunc) {
+	Registry.MustRegister(
+		collectors.NewDBStatsCollector(db, "gophermart"),
+		&queueCollector{
+			stats: stats,
+			total: prometheus.NewDesc(namespace+"_order_queue_depth",
+				"Orders waiting for a final status from the accrual system.", nil, nil),
+			ready: prometheus.NewDesc(namespace+"_order_queue_ready",
+				"Queued orders whose next check is already due.", nil, nil),
+			deadLettered: prometheus.NewDesc(namespace+"_order_queue_dead_lettered",
+				"Orders that ran out of accrual check attempts and wait for a manual requeue.", nil, nil),
+		},
+	)
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.total
+	ch <- c.ready
+	ch <- c.deadLettered
+}
+
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), queueStatsTimeout)
+	defer cancel()
+
+	total, ready, deadLettered, err := c.stats(ctx)
+	if err != nil {
+		logger.Log.Errorf("collect order queue stats error: %v", err)
+		ch <- prometheus.NewInvalidMetric(c.total, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(total))
+	ch <- prometheus.MustNewConstMetric(c.ready, prometheus.GaugeValue, float64(ready))
+	ch <- prometheus.MustNewConstMetric(c.deadLettered, prometheus.GaugeValue, float64(deadLettered))
+}
@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/arseniy96/bonus-program/internal/apierror"
+)
+
+// AdminMiddleware пропускает только запросы с токеном администратора в заголовке Authorization.
+// Пустой token не подходит ни к одному запросу.
+func AdminMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			apierror.Abort(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "missing or invalid admin token"))
+			return
+		}
+
+		c.Next()
+	}
+}
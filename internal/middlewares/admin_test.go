@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminMiddleware(t *testing.T) {
+	const token = "admin-token-admin-token"
+
+	tests := []struct {
+		name       string
+		token      string
+		header     string
+		statusCode int
+	}{
+		{
+			name:       "valid token",
+			token:      token,
+			header:     "Bearer " + token,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "token without prefix",
+			token:      token,
+			header:     token,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "wrong token",
+			token:      token,
+			header:     "Bearer admin-token",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "missing header",
+			token:      token,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "admin api is disabled",
+			token:      "",
+			header:     "Bearer ",
+			statusCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(ErrorRenderer())
+			r.GET("/admin", AdminMiddleware(tt.token), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req, _ := http.NewRequest("GET", "/admin", nil)
+			req.Header.Set("Authorization", tt.header)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+		})
+	}
+}
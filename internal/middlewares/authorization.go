@@ -1,58 +1,44 @@
 package middlewares
 
 import (
-	"context"
 	"fmt"
 	"net/http"
-	"time"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
-	"github.com/arseniy96/bonus-program/internal/logger"
-	"github.com/arseniy96/bonus-program/internal/services/mycrypto"
-	"github.com/arseniy96/bonus-program/internal/store"
+	"github.com/arseniy96/bonus-program/internal/apierror"
+	"github.com/arseniy96/bonus-program/internal/services/authtoken"
 )
 
-type repository interface {
-	FindUserByToken(context.Context, string) (*store.User, error)
+type tokenParser interface {
+	Parse(string) (*authtoken.Claims, error)
 }
 
-func AuthMiddleware(r repository) gin.HandlerFunc {
+// AuthMiddleware проверяет JWT из заголовка Authorization. Токен самодостаточен, поэтому в базу не ходим:
+// отзыв сессии не отменяет уже выпущенный токен доступа, он просто не будет продлён через refresh.
+// Подключается только к группе маршрутов, требующих аутентификации.
+func AuthMiddleware(keys tokenParser) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		path := c.Request.URL.Path
-		if path == `/api/user/register` || path == `/api/user/login` {
-			c.Next()
-			return
-		}
-
-		authHeader := c.GetHeader("Authorization")
-		if err := checkHeader(r, authHeader); err != nil {
-			c.AbortWithError(http.StatusUnauthorized, err)
+		claims, err := checkHeader(keys, c.GetHeader("Authorization"))
+		if err != nil {
+			apierror.Abort(c, apierror.Wrap(err, http.StatusUnauthorized, apierror.CodeUnauthorized, "missing or invalid access token"))
 			return
 		}
+		SetPrincipal(c, &Principal{
+			UserID:    claims.UserID,
+			Login:     claims.Login,
+			SessionID: claims.SessionID,
+		})
 
 		c.Next()
 	}
 }
 
-func checkHeader(r repository, header string) error {
+func checkHeader(keys tokenParser, header string) (*authtoken.Claims, error) {
 	if len(header) == 0 {
-		return fmt.Errorf("missing Authorization header")
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	token := mycrypto.HashFunc(header)
-
-	user, err := r.FindUserByToken(ctx, token)
-	if err != nil {
-		logger.Log.Errorf("find user error: %v", err)
-		return fmt.Errorf("invalid token")
+		return nil, fmt.Errorf("missing Authorization header")
 	}
 
-	if user.TokenExpAt.Before(time.Now()) {
-		return fmt.Errorf("token expired")
-	}
-	return nil
+	return keys.Parse(strings.TrimPrefix(header, "Bearer "))
 }
@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arseniy96/bonus-program/internal/services/authtoken"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	keys, err := authtoken.NewKeys("test:test-secret-test-secret-test-secret", "")
+	require.NoError(t, err)
+	token, err := keys.Issue(authtoken.Claims{UserID: 1, Login: "gopher", SessionID: "session"}, time.Hour)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		header     string
+		statusCode int
+		principal  *Principal
+	}{
+		{
+			name:       "valid token",
+			header:     token,
+			statusCode: http.StatusOK,
+			principal:  &Principal{UserID: 1, Login: "gopher", SessionID: "session"},
+		},
+		{
+			name:       "bearer prefix",
+			header:     "Bearer " + token,
+			statusCode: http.StatusOK,
+			principal:  &Principal{UserID: 1, Login: "gopher", SessionID: "session"},
+		},
+		{
+			name:       "missing header",
+			header:     "",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid token",
+			header:     "wrong_auth_token",
+			statusCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *Principal
+			r := gin.New()
+			r.Use(ErrorRenderer())
+			r.GET("/private", AuthMiddleware(keys), func(c *gin.Context) {
+				got, _ = GetPrincipal(c)
+				c.Status(http.StatusOK)
+			})
+
+			req, _ := http.NewRequest("GET", "/private", nil)
+			req.Header.Set("Authorization", tt.header)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Equal(t, tt.principal, got)
+		})
+	}
+}
+
+func TestGetPrincipal_Unauthenticated(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	_, ok := GetPrincipal(c)
+	assert.False(t, ok)
+}
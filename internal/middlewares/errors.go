@@ -0,0 +1,17 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/arseniy96/bonus-program/internal/apierror"
+)
+
+// ErrorRenderer отдаёт клиенту ошибки, с которыми обработчики прервали запрос через apierror.Abort,
+// в формате application/problem+json. Регистрируется последней из middleware, после gin.Recovery: так ответ
+// уже записан, когда статус видят RequestLogger и Metrics, а паника в обработчике проходит мимо неё к Recovery.
+func ErrorRenderer() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		apierror.Render(c)
+	}
+}
@@ -0,0 +1,172 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/arseniy96/bonus-program/internal/apierror"
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/services/ctxutil"
+	"github.com/arseniy96/bonus-program/internal/store"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// IdempotencyReservationTimeout — через сколько резерв ключа без сохранённого ответа считается брошенным
+	// (процесс упал посреди запроса) и ключ можно использовать снова. Заведомо больше времени обработки запроса.
+	IdempotencyReservationTimeout = time.Minute
+	// IdempotencyKeyTTL — сколько хранятся ключи идемпотентности; более старые удаляет Server.CleanupWorker
+	IdempotencyKeyTTL = 24 * time.Hour
+
+	// IdempotencyMaxBodySize — ограничение на тело запроса с Idempotency-Key: тело читается целиком,
+	// чтобы посчитать его хэш. Идемпотентные методы принимают только короткие тела.
+	IdempotencyMaxBodySize = 64 << 10
+
+	idempotencyKeyMaxLength   = 255
+	idempotencyRequestTimeout = 3 * time.Second
+)
+
+type idempotencyRepository interface {
+	ReserveIdempotencyKey(context.Context, int, string, string, time.Duration) (*store.IdempotencyKey, bool, error)
+	SaveIdempotencyResponse(context.Context, int, string, int, string, string) error
+	DeleteIdempotencyKey(context.Context, int, string) error
+}
+
+// IdempotencyMiddleware запоминает ответ на запрос с заголовком Idempotency-Key и на повтор с тем же ключом
+// возвращает его, не выполняя запрос ещё раз. Повтор с другим телом получает 422, а повтор, пока исходный
+// запрос ещё выполняется, — 409. Ответы 5xx и паника обработчика освобождают ключ, чтобы такой запрос можно
+// было повторить; если процесс упал, ключ освобождается через IdempotencyReservationTimeout.
+func IdempotencyMiddleware(r idempotencyRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > idempotencyKeyMaxLength {
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeBadRequest, "idempotency key is too long"))
+			return
+		}
+
+		principal, ok := GetPrincipal(c)
+		if !ok {
+			apierror.Abort(c, apierror.ErrUnauthorized)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(ctxutil.WithoutCancel(c.Request.Context()), idempotencyRequestTimeout)
+		defer cancel()
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, IdempotencyMaxBodySize))
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			apierror.Abort(c, apierror.Wrap(err, http.StatusRequestEntityTooLarge, apierror.CodeRequestTooLarge,
+				fmt.Sprintf("request body must not exceed %d bytes", IdempotencyMaxBodySize)))
+			return
+		}
+		if err != nil {
+			apierror.Abort(c, apierror.Wrap(err, http.StatusBadRequest, apierror.CodeBadRequest, "failed to read request body"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		requestHash := hashRequest(c.Request.Method, c.FullPath(), body)
+
+		stored, reserved, err := r.ReserveIdempotencyKey(ctx, principal.UserID, key, requestHash, IdempotencyReservationTimeout)
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Errorf("reserve idempotency key error: %v", err)
+			apierror.Abort(c, err)
+			return
+		}
+		if !reserved {
+			replay(c, stored, requestHash)
+			return
+		}
+
+		defer func() {
+			if rec := recover(); rec != nil {
+				deleteReservation(c, r, principal.UserID, key)
+				panic(rec)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		// ошибку нужно отрисовать до того, как ответ будет сохранён
+		apierror.Render(c)
+
+		saveCtx, saveCancel := context.WithTimeout(ctxutil.WithoutCancel(c.Request.Context()), idempotencyRequestTimeout)
+		defer saveCancel()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			err = r.DeleteIdempotencyKey(saveCtx, principal.UserID, key)
+		} else {
+			err = r.SaveIdempotencyResponse(saveCtx, principal.UserID, key, status, recorder.Header().Get("Content-Type"), recorder.body.String())
+		}
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Errorw("save idempotency key error",
+				"user_id", principal.UserID,
+				"error", err)
+		}
+	}
+}
+
+func deleteReservation(c *gin.Context, r idempotencyRepository, userID int, key string) {
+	ctx, cancel := context.WithTimeout(ctxutil.WithoutCancel(c.Request.Context()), idempotencyRequestTimeout)
+	defer cancel()
+
+	if err := r.DeleteIdempotencyKey(ctx, userID, key); err != nil {
+		logger.FromContext(c.Request.Context()).Errorw("delete idempotency key error",
+			"user_id", userID,
+			"error", err)
+	}
+}
+
+func replay(c *gin.Context, stored *store.IdempotencyKey, requestHash string) {
+	if stored.RequestHash != requestHash {
+		apierror.Abort(c, apierror.New(http.StatusUnprocessableEntity, apierror.CodeIdempotencyKeyMismatch, "idempotency key was used with another request"))
+		return
+	}
+	if stored.StatusCode == 0 {
+		apierror.Abort(c, apierror.New(http.StatusConflict, apierror.CodeIdempotencyKeyInProgress, "request with this idempotency key is in progress"))
+		return
+	}
+
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Data(stored.StatusCode, stored.ContentType, []byte(stored.ResponseBody))
+	c.Abort()
+}
+
+func hashRequest(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder копирует тело ответа, чтобы его можно было сохранить.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
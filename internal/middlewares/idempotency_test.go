@@ -0,0 +1,171 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/store"
+)
+
+func init() {
+	logger.Log = zap.NewNop().Sugar()
+}
+
+type idempotencyKeysRepository struct {
+	mu   sync.Mutex
+	keys map[string]store.IdempotencyKey
+}
+
+func (r *idempotencyKeysRepository) ReserveIdempotencyKey(_ context.Context, userID int, key, requestHash string, staleAfter time.Duration) (*store.IdempotencyKey, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if k, ok := r.keys[key]; ok && (k.StatusCode != 0 || time.Since(k.CreatedAt) < staleAfter) {
+		return &k, false, nil
+	}
+	k := store.IdempotencyKey{Key: key, UserID: userID, RequestHash: requestHash, CreatedAt: time.Now()}
+	r.keys[key] = k
+	return &k, true, nil
+}
+
+func (r *idempotencyKeysRepository) SaveIdempotencyResponse(_ context.Context, _ int, key string, statusCode int, contentType, body string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := r.keys[key]
+	k.StatusCode, k.ContentType, k.ResponseBody = statusCode, contentType, body
+	r.keys[key] = k
+	return nil
+}
+
+func (r *idempotencyKeysRepository) DeleteIdempotencyKey(_ context.Context, _ int, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.keys, key)
+	return nil
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	repo := &idempotencyKeysRepository{keys: map[string]store.IdempotencyKey{}}
+	calls := 0
+
+	r := gin.New()
+	r.Use(ErrorRenderer())
+	r.Use(func(c *gin.Context) { SetPrincipal(c, &Principal{UserID: 1}) })
+	r.POST("/api/user/balance/withdraw", IdempotencyMiddleware(repo), func(c *gin.Context) {
+		calls++
+		if c.GetHeader("X-Fail") != "" {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"call": calls})
+	})
+
+	send := func(key, body string, headers ...string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/user/balance/withdraw", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send("key-1", `{"order":"123","sum":5}`)
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, `{"call":1}`, first.Body.String())
+
+	replayed := send("key-1", `{"order":"123","sum":5}`)
+	assert.Equal(t, http.StatusOK, replayed.Code)
+	assert.Equal(t, `{"call":1}`, replayed.Body.String())
+	assert.Equal(t, "true", replayed.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, first.Header().Get("Content-Type"), replayed.Header().Get("Content-Type"))
+
+	mismatched := send("key-1", `{"order":"123","sum":50}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, mismatched.Code)
+
+	withoutKey := send("", `{"order":"123","sum":5}`)
+	assert.Equal(t, `{"call":2}`, withoutKey.Body.String())
+
+	failed := send("key-2", `{"order":"123","sum":5}`, "X-Fail", "yes")
+	assert.Equal(t, http.StatusInternalServerError, failed.Code)
+	retried := send("key-2", `{"order":"123","sum":5}`)
+	assert.Equal(t, http.StatusOK, retried.Code)
+	assert.Equal(t, `{"call":4}`, retried.Body.String())
+
+	// слишком большое тело отклоняется до резерва ключа
+	tooLarge := send("key-3", strings.Repeat("a", IdempotencyMaxBodySize+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, tooLarge.Code)
+	assert.Contains(t, tooLarge.Body.String(), `"code":"request_too_large"`)
+	assert.NotContains(t, repo.keys, "key-3")
+
+	assert.Equal(t, 4, calls)
+}
+
+func TestIdempotencyMiddleware_AbandonedReservation(t *testing.T) {
+	repo := &idempotencyKeysRepository{keys: map[string]store.IdempotencyKey{}}
+	calls := 0
+
+	r := gin.New()
+	r.Use(gin.CustomRecovery(func(c *gin.Context, _ interface{}) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	r.Use(ErrorRenderer())
+	r.Use(func(c *gin.Context) { SetPrincipal(c, &Principal{UserID: 1}) })
+	r.POST("/api/user/orders", IdempotencyMiddleware(repo), func(c *gin.Context) {
+		calls++
+		if c.GetHeader("X-Panic") != "" {
+			panic("handler failed")
+		}
+		c.JSON(http.StatusAccepted, gin.H{"call": calls})
+	})
+
+	send := func(key string, headers ...string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/user/orders", strings.NewReader("12345678903"))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	hash := hashRequest("POST", "/api/user/orders", []byte("12345678903"))
+
+	// процесс упал, пока запрос выполнялся: резерв остался без ответа
+	repo.keys["fresh"] = store.IdempotencyKey{Key: "fresh", UserID: 1, RequestHash: hash, CreatedAt: time.Now()}
+	repo.keys["stale"] = store.IdempotencyKey{Key: "stale", UserID: 1, RequestHash: hash,
+		CreatedAt: time.Now().Add(-2 * IdempotencyReservationTimeout)}
+
+	inProgress := send("fresh")
+	assert.Equal(t, http.StatusConflict, inProgress.Code)
+	assert.Equal(t, 0, calls)
+
+	reclaimed := send("stale")
+	assert.Equal(t, http.StatusAccepted, reclaimed.Code)
+	assert.Equal(t, `{"call":1}`, reclaimed.Body.String())
+	replayed := send("stale")
+	assert.Equal(t, `{"call":1}`, replayed.Body.String())
+	assert.Equal(t, "true", replayed.Header().Get(IdempotentReplayedHeader))
+
+	// паника обработчика освобождает ключ сразу
+	panicked := send("panic", "X-Panic", "yes")
+	assert.Equal(t, http.StatusInternalServerError, panicked.Code)
+	retried := send("panic")
+	assert.Equal(t, http.StatusAccepted, retried.Code)
+	assert.Equal(t, `{"call":3}`, retried.Body.String())
+}
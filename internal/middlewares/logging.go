@@ -0,0 +1,185 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/services/mycrypto"
+)
+
+const (
+	RequestIDHeader    = "X-Request-ID"
+	requestIDMaxLength = 128
+	requestIDSize      = 16
+	// logBodyMaxSize — сколько байт тела запроса попадает в лог на уровне debug
+	logBodyMaxSize = 4 << 10
+	redacted       = "[REDACTED]"
+)
+
+var requestIDPattern = regexp.MustCompile(`^[a-zA-Z0-9._:-]+$`)
+
+// Заголовки и поля тела, значения которых не должны попадать в лог.
+var (
+	sensitiveHeaders = map[string]bool{
+		"Authorization": true,
+		"Cookie":        true,
+		"Set-Cookie":    true,
+	}
+	sensitiveFields = map[string]bool{
+		"password":      true,
+		"refresh_token": true,
+		"access_token":  true,
+	}
+)
+
+// RequestLogger логирует каждый запрос: метод, путь, статус, время обработки, размер ответа и пользователя.
+// ID запроса берётся из заголовка X-Request-ID или генерируется, возвращается клиенту в том же заголовке
+// и кладётся в контекст запроса (см. logger.FromContext). На уровне debug логируются также заголовки и тело
+// запроса, из которых вырезаются токены и пароли.
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), requestID))
+
+		debug := logger.Log.Desugar().Core().Enabled(zapcore.DebugLevel)
+		var body []byte
+		if debug {
+			body = peekBody(c.Request)
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		fields := []interface{}{
+			logger.RequestIDField, requestID,
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", status,
+			"latency", time.Since(start),
+			"size", c.Writer.Size(),
+			"client_ip", c.ClientIP(),
+		}
+		if principal, ok := GetPrincipal(c); ok {
+			fields = append(fields, "user_id", principal.UserID)
+		}
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.HasTraceID() {
+			fields = append(fields, "trace_id", sc.TraceID().String())
+		}
+		if len(c.Errors) > 0 {
+			fields = append(fields, "errors", c.Errors.String())
+		}
+		if debug {
+			fields = append(fields,
+				"request_headers", redactHeaders(c.Request.Header),
+				"request_body", redactBody(body),
+				"response_headers", redactHeaders(c.Writer.Header()))
+		}
+
+		switch {
+		case status >= http.StatusInternalServerError:
+			logger.Log.Errorw("request", fields...)
+		default:
+			logger.Log.Infow("request", fields...)
+		}
+	}
+}
+
+func validRequestID(requestID string) bool {
+	return requestID != "" && len(requestID) <= requestIDMaxLength && requestIDPattern.MatchString(requestID)
+}
+
+func newRequestID() string {
+	requestID, err := mycrypto.CreateRandomToken(requestIDSize)
+	if err != nil {
+		// без ID запрос всё равно нужно обработать
+		logger.Log.Errorf("generate request id error: %v", err)
+	}
+	return requestID
+}
+
+// peekBody читает начало тела запроса для лога и возвращает его обратно в запрос целиком.
+func peekBody(r *http.Request) []byte {
+	if r.Body == nil {
+		return nil
+	}
+	head, err := io.ReadAll(io.LimitReader(r.Body, logBodyMaxSize))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+	if err != nil {
+		return nil
+	}
+	return head
+}
+
+func redactHeaders(h http.Header) map[string]string {
+	res := make(map[string]string, len(h))
+	for name, values := range h {
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			res[name] = redacted
+			continue
+		}
+		res[name] = strings.Join(values, ", ")
+	}
+	return res
+}
+
+// redactBody заменяет значения чувствительных полей JSON. Тело, которое не удалось разобрать
+// (не JSON или обрезанное), в лог не попадает, если в нём встречается имя такого поля.
+func redactBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		lower := strings.ToLower(string(body))
+		for field := range sensitiveFields {
+			if strings.Contains(lower, field) {
+				return redacted
+			}
+		}
+		return string(body)
+	}
+
+	res, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return redacted
+	}
+	return string(res)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if sensitiveFields[strings.ToLower(k)] {
+				val[k] = redacted
+				continue
+			}
+			val[k] = redactValue(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redactValue(item)
+		}
+	}
+	return v
+}
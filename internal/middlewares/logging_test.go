@@ -0,0 +1,127 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/arseniy96/bonus-program/internal/logger"
+)
+
+func observeLogs(t *testing.T, level zapcore.Level) *observer.ObservedLogs {
+	core, logs := observer.New(level)
+	prev := logger.Log
+	logger.Log = zap.New(core).Sugar()
+	t.Cleanup(func() { logger.Log = prev })
+	return logs
+}
+
+func TestRequestLogger(t *testing.T) {
+	tests := []struct {
+		name          string
+		requestID     string
+		wantRequestID string
+	}{
+		{name: "request id from client", requestID: "client-id-1", wantRequestID: "client-id-1"},
+		{name: "generated request id", requestID: ""},
+		{name: "invalid request id is replaced", requestID: "bad id\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := observeLogs(t, zapcore.InfoLevel)
+
+			var ctxRequestID string
+			r := gin.New()
+			r.Use(RequestLogger())
+			r.POST("/api/user/orders", func(c *gin.Context) {
+				SetPrincipal(c, &Principal{UserID: 7})
+				ctxRequestID = logger.RequestID(c.Request.Context())
+				c.String(http.StatusAccepted, "order saved")
+			})
+
+			req, _ := http.NewRequest("POST", "/api/user/orders", strings.NewReader("12345678903"))
+			if tt.requestID != "" {
+				req.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			requestID := w.Header().Get(RequestIDHeader)
+			require.NotEmpty(t, requestID)
+			if tt.wantRequestID != "" {
+				assert.Equal(t, tt.wantRequestID, requestID)
+			} else {
+				assert.NotEqual(t, tt.requestID, requestID)
+			}
+			assert.Equal(t, requestID, ctxRequestID)
+
+			entries := logs.All()
+			require.Len(t, entries, 1)
+			fields := entries[0].ContextMap()
+			assert.Equal(t, requestID, fields[logger.RequestIDField])
+			assert.Equal(t, "POST", fields["method"])
+			assert.Equal(t, "/api/user/orders", fields["path"])
+			assert.EqualValues(t, http.StatusAccepted, fields["status"])
+			assert.EqualValues(t, len("order saved"), fields["size"])
+			assert.EqualValues(t, 7, fields["user_id"])
+			assert.Contains(t, fields, "latency")
+		})
+	}
+}
+
+func TestRequestLogger_Redaction(t *testing.T) {
+	logs := observeLogs(t, zapcore.DebugLevel)
+
+	var body string
+	r := gin.New()
+	r.Use(RequestLogger())
+	r.POST("/api/user/login", func(c *gin.Context) {
+		b, _ := c.GetRawData()
+		body = string(b)
+		c.Header("Authorization", "issued-token")
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	payload := `{"login":"gopher","password":"secret"}`
+	req, _ := http.NewRequest("POST", "/api/user/login", strings.NewReader(payload))
+	req.Header.Set("Authorization", "Bearer token")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// обработчик получает тело целиком
+	assert.Equal(t, payload, body)
+
+	entries := logs.All()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.JSONEq(t, `{"login":"gopher","password":"[REDACTED]"}`, fields["request_body"].(string))
+	assert.Equal(t, "[REDACTED]", fields["request_headers"].(map[string]string)["Authorization"])
+	assert.Equal(t, "[REDACTED]", fields["response_headers"].(map[string]string)["Authorization"])
+	assert.NotContains(t, entries[0].Message, "secret")
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "empty", body: "", want: ""},
+		{name: "plain text", body: "12345678903", want: "12345678903"},
+		{name: "nested", body: `{"a":[{"refresh_token":"x"}],"Password":"y"}`, want: `{"Password":"[REDACTED]","a":[{"refresh_token":"[REDACTED]"}]}`},
+		{name: "truncated json with password", body: `{"login":"gopher","password":"sec`, want: "[REDACTED]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, redactBody([]byte(tt.body)))
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/arseniy96/bonus-program/internal/metrics"
+)
+
+// unmatchedRoute — метка для запросов, не попавших ни в один маршрут, чтобы произвольные пути
+// не плодили временные ряды.
+const unmatchedRoute = "unmatched"
+
+// Metrics учитывает время обработки запросов по шаблону маршрута и статусу ответа.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		metrics.HTTPRequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
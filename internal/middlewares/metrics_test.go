@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arseniy96/bonus-program/internal/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	r := gin.New()
+	r.Use(Metrics())
+	r.GET("/api/user/orders/:number", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for _, path := range []string{"/api/user/orders/1", "/api/user/orders/2", "/unknown"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// запросы объединяются по шаблону маршрута, а не по фактическому пути
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.HTTPRequestDuration))
+	assert.Equal(t, uint64(2), sampleCount(t, "GET", "/api/user/orders/:number", "204"))
+	assert.Equal(t, uint64(1), sampleCount(t, "GET", "unmatched", "404"))
+}
+
+func sampleCount(t *testing.T, labels ...string) uint64 {
+	observer, err := metrics.HTTPRequestDuration.GetMetricWithLabelValues(labels...)
+	require.NoError(t, err)
+
+	var m dto.Metric
+	require.NoError(t, observer.(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// principalKey — ключ в контексте gin, под которым AuthMiddleware сохраняет аутентифицированного пользователя.
+const principalKey = "principal"
+
+// Principal — пользователь, от имени которого выполняется запрос. Заполняется из проверенного токена доступа.
+type Principal struct {
+	UserID    int
+	Login     string
+	SessionID string
+}
+
+// SetPrincipal сохраняет пользователя в контексте запроса.
+func SetPrincipal(c *gin.Context, p *Principal) {
+	c.Set(principalKey, p)
+}
+
+// GetPrincipal возвращает пользователя, сохранённого AuthMiddleware. ok == false, если запрос не аутентифицирован.
+func GetPrincipal(c *gin.Context) (*Principal, bool) {
+	v, exists := c.Get(principalKey)
+	if !exists {
+		return nil, false
+	}
+	p, ok := v.(*Principal)
+	if !ok || p == nil || p.UserID == 0 {
+		return nil, false
+	}
+	return p, true
+}
@@ -9,6 +9,8 @@ import (
 	reflect "reflect"
 	time "time"
 
+	accrual "github.com/arseniy96/bonus-program/internal/services/accrual"
+	money "github.com/arseniy96/bonus-program/internal/services/money"
 	store "github.com/arseniy96/bonus-program/internal/store"
 	gomock "github.com/golang/mock/gomock"
 )
@@ -36,6 +38,21 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// ClaimOrderJobs mocks base method.
+func (m *MockRepository) ClaimOrderJobs(arg0 context.Context, arg1 int, arg2 time.Duration) ([]store.OrderJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimOrderJobs", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]store.OrderJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimOrderJobs indicates an expected call of ClaimOrderJobs.
+func (mr *MockRepositoryMockRecorder) ClaimOrderJobs(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimOrderJobs", reflect.TypeOf((*MockRepository)(nil).ClaimOrderJobs), arg0, arg1, arg2)
+}
+
 // CreateOrder mocks base method.
 func (m *MockRepository) CreateOrder(arg0 context.Context, arg1 int, arg2, arg3 string) (*store.Order, error) {
 	m.ctrl.T.Helper()
@@ -51,20 +68,123 @@ func (mr *MockRepositoryMockRecorder) CreateOrder(arg0, arg1, arg2, arg3 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrder", reflect.TypeOf((*MockRepository)(nil).CreateOrder), arg0, arg1, arg2, arg3)
 }
 
-// CreateUser mocks base method.
-func (m *MockRepository) CreateUser(arg0 context.Context, arg1, arg2 string) error {
+// CreateSession mocks base method.
+func (m *MockRepository) CreateSession(arg0 context.Context, arg1 *store.Session, arg2 string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "CreateSession", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockRepositoryMockRecorder) CreateSession(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockRepository)(nil).CreateSession), arg0, arg1, arg2)
+}
+
+// CreateUser mocks base method.
+func (m *MockRepository) CreateUser(arg0 context.Context, arg1, arg2 string) (*store.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*store.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // CreateUser indicates an expected call of CreateUser.
 func (mr *MockRepositoryMockRecorder) CreateUser(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockRepository)(nil).CreateUser), arg0, arg1, arg2)
 }
 
+// DeadLetterOrderJob mocks base method.
+func (m *MockRepository) DeadLetterOrderJob(arg0 context.Context, arg1 int, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeadLetterOrderJob", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeadLetterOrderJob indicates an expected call of DeadLetterOrderJob.
+func (mr *MockRepositoryMockRecorder) DeadLetterOrderJob(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeadLetterOrderJob", reflect.TypeOf((*MockRepository)(nil).DeadLetterOrderJob), arg0, arg1, arg2)
+}
+
+// DeadLetteredOrderJobs mocks base method.
+func (m *MockRepository) DeadLetteredOrderJobs(arg0 context.Context, arg1, arg2 int) ([]store.OrderJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeadLetteredOrderJobs", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]store.OrderJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeadLetteredOrderJobs indicates an expected call of DeadLetteredOrderJobs.
+func (mr *MockRepositoryMockRecorder) DeadLetteredOrderJobs(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeadLetteredOrderJobs", reflect.TypeOf((*MockRepository)(nil).DeadLetteredOrderJobs), arg0, arg1, arg2)
+}
+
+// DeleteExpiredFailedLogins mocks base method.
+func (m *MockRepository) DeleteExpiredFailedLogins(arg0 context.Context, arg1 time.Duration) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredFailedLogins", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredFailedLogins indicates an expected call of DeleteExpiredFailedLogins.
+func (mr *MockRepositoryMockRecorder) DeleteExpiredFailedLogins(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredFailedLogins", reflect.TypeOf((*MockRepository)(nil).DeleteExpiredFailedLogins), arg0, arg1)
+}
+
+// DeleteExpiredIdempotencyKeys mocks base method.
+func (m *MockRepository) DeleteExpiredIdempotencyKeys(arg0 context.Context, arg1 time.Duration) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredIdempotencyKeys", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredIdempotencyKeys indicates an expected call of DeleteExpiredIdempotencyKeys.
+func (mr *MockRepositoryMockRecorder) DeleteExpiredIdempotencyKeys(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredIdempotencyKeys", reflect.TypeOf((*MockRepository)(nil).DeleteExpiredIdempotencyKeys), arg0, arg1)
+}
+
+// DeleteIdempotencyKey mocks base method.
+func (m *MockRepository) DeleteIdempotencyKey(arg0 context.Context, arg1 int, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIdempotencyKey", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteIdempotencyKey indicates an expected call of DeleteIdempotencyKey.
+func (mr *MockRepositoryMockRecorder) DeleteIdempotencyKey(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIdempotencyKey", reflect.TypeOf((*MockRepository)(nil).DeleteIdempotencyKey), arg0, arg1, arg2)
+}
+
+// FindActiveSessionsByUserID mocks base method.
+func (m *MockRepository) FindActiveSessionsByUserID(arg0 context.Context, arg1 int) ([]store.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindActiveSessionsByUserID", arg0, arg1)
+	ret0, _ := ret[0].([]store.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindActiveSessionsByUserID indicates an expected call of FindActiveSessionsByUserID.
+func (mr *MockRepositoryMockRecorder) FindActiveSessionsByUserID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindActiveSessionsByUserID", reflect.TypeOf((*MockRepository)(nil).FindActiveSessionsByUserID), arg0, arg1)
+}
+
 // FindBonusTransactionsByUserID mocks base method.
 func (m *MockRepository) FindBonusTransactionsByUserID(arg0 context.Context, arg1 int) ([]store.BonusTransaction, error) {
 	m.ctrl.T.Helper()
@@ -110,6 +230,21 @@ func (mr *MockRepositoryMockRecorder) FindOrdersByUserID(arg0, arg1 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrdersByUserID", reflect.TypeOf((*MockRepository)(nil).FindOrdersByUserID), arg0, arg1)
 }
 
+// FindUserByID mocks base method.
+func (m *MockRepository) FindUserByID(arg0 context.Context, arg1 int) (*store.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUserByID", arg0, arg1)
+	ret0, _ := ret[0].(*store.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUserByID indicates an expected call of FindUserByID.
+func (mr *MockRepositoryMockRecorder) FindUserByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserByID", reflect.TypeOf((*MockRepository)(nil).FindUserByID), arg0, arg1)
+}
+
 // FindUserByLogin mocks base method.
 func (m *MockRepository) FindUserByLogin(arg0 context.Context, arg1 string) (*store.User, error) {
 	m.ctrl.T.Helper()
@@ -125,38 +260,197 @@ func (mr *MockRepositoryMockRecorder) FindUserByLogin(arg0, arg1 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserByLogin", reflect.TypeOf((*MockRepository)(nil).FindUserByLogin), arg0, arg1)
 }
 
-// FindUserByToken mocks base method.
-func (m *MockRepository) FindUserByToken(arg0 context.Context, arg1 string) (*store.User, error) {
+// GetWithdrawalSumByUserID mocks base method.
+func (m *MockRepository) GetWithdrawalSumByUserID(arg0 context.Context, arg1 int) (money.Amount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWithdrawalSumByUserID", arg0, arg1)
+	ret0, _ := ret[0].(money.Amount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWithdrawalSumByUserID indicates an expected call of GetWithdrawalSumByUserID.
+func (mr *MockRepositoryMockRecorder) GetWithdrawalSumByUserID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithdrawalSumByUserID", reflect.TypeOf((*MockRepository)(nil).GetWithdrawalSumByUserID), arg0, arg1)
+}
+
+// MigrationStatus mocks base method.
+func (m *MockRepository) MigrationStatus(arg0 context.Context) (*store.MigrationStatus, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindUserByToken", arg0, arg1)
-	ret0, _ := ret[0].(*store.User)
+	ret := m.ctrl.Call(m, "MigrationStatus", arg0)
+	ret0, _ := ret[0].(*store.MigrationStatus)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// FindUserByToken indicates an expected call of FindUserByToken.
-func (mr *MockRepositoryMockRecorder) FindUserByToken(arg0, arg1 interface{}) *gomock.Call {
+// MigrationStatus indicates an expected call of MigrationStatus.
+func (mr *MockRepositoryMockRecorder) MigrationStatus(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserByToken", reflect.TypeOf((*MockRepository)(nil).FindUserByToken), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrationStatus", reflect.TypeOf((*MockRepository)(nil).MigrationStatus), arg0)
 }
 
-// GetWithdrawalSumByUserID mocks base method.
-func (m *MockRepository) GetWithdrawalSumByUserID(arg0 context.Context, arg1 int) (int, error) {
+// Ping mocks base method.
+func (m *MockRepository) Ping(arg0 context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetWithdrawalSumByUserID", arg0, arg1)
+	ret := m.ctrl.Call(m, "Ping", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockRepositoryMockRecorder) Ping(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockRepository)(nil).Ping), arg0)
+}
+
+// RecoverOrderJobs mocks base method.
+func (m *MockRepository) RecoverOrderJobs(arg0 context.Context, arg1, arg2 int) (*store.RecoveryPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecoverOrderJobs", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*store.RecoveryPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecoverOrderJobs indicates an expected call of RecoverOrderJobs.
+func (mr *MockRepositoryMockRecorder) RecoverOrderJobs(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecoverOrderJobs", reflect.TypeOf((*MockRepository)(nil).RecoverOrderJobs), arg0, arg1, arg2)
+}
+
+// ReleaseOrderJob mocks base method.
+func (m *MockRepository) ReleaseOrderJob(arg0 context.Context, arg1 int, arg2 time.Duration, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseOrderJob", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseOrderJob indicates an expected call of ReleaseOrderJob.
+func (mr *MockRepositoryMockRecorder) ReleaseOrderJob(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseOrderJob", reflect.TypeOf((*MockRepository)(nil).ReleaseOrderJob), arg0, arg1, arg2, arg3)
+}
+
+// RequeueOrderJob mocks base method.
+func (m *MockRepository) RequeueOrderJob(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequeueOrderJob", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequeueOrderJob indicates an expected call of RequeueOrderJob.
+func (mr *MockRepositoryMockRecorder) RequeueOrderJob(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequeueOrderJob", reflect.TypeOf((*MockRepository)(nil).RequeueOrderJob), arg0, arg1)
+}
+
+// RescheduleOrderJob mocks base method.
+func (m *MockRepository) RescheduleOrderJob(arg0 context.Context, arg1 int, arg2 time.Duration, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RescheduleOrderJob", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RescheduleOrderJob indicates an expected call of RescheduleOrderJob.
+func (mr *MockRepositoryMockRecorder) RescheduleOrderJob(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RescheduleOrderJob", reflect.TypeOf((*MockRepository)(nil).RescheduleOrderJob), arg0, arg1, arg2, arg3)
+}
+
+// ReserveIdempotencyKey mocks base method.
+func (m *MockRepository) ReserveIdempotencyKey(arg0 context.Context, arg1 int, arg2, arg3 string, arg4 time.Duration) (*store.IdempotencyKey, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveIdempotencyKey", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(*store.IdempotencyKey)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReserveIdempotencyKey indicates an expected call of ReserveIdempotencyKey.
+func (mr *MockRepositoryMockRecorder) ReserveIdempotencyKey(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveIdempotencyKey", reflect.TypeOf((*MockRepository)(nil).ReserveIdempotencyKey), arg0, arg1, arg2, arg3, arg4)
+}
+
+// RevokeOtherSessions mocks base method.
+func (m *MockRepository) RevokeOtherSessions(arg0 context.Context, arg1 int, arg2 string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeOtherSessions", arg0, arg1, arg2)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetWithdrawalSumByUserID indicates an expected call of GetWithdrawalSumByUserID.
-func (mr *MockRepositoryMockRecorder) GetWithdrawalSumByUserID(arg0, arg1 interface{}) *gomock.Call {
+// RevokeOtherSessions indicates an expected call of RevokeOtherSessions.
+func (mr *MockRepositoryMockRecorder) RevokeOtherSessions(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithdrawalSumByUserID", reflect.TypeOf((*MockRepository)(nil).GetWithdrawalSumByUserID), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOtherSessions", reflect.TypeOf((*MockRepository)(nil).RevokeOtherSessions), arg0, arg1, arg2)
+}
+
+// RevokeSession mocks base method.
+func (m *MockRepository) RevokeSession(arg0 context.Context, arg1 int, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockRepositoryMockRecorder) RevokeSession(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockRepository)(nil).RevokeSession), arg0, arg1, arg2)
+}
+
+// RotateRefreshToken mocks base method.
+func (m *MockRepository) RotateRefreshToken(arg0 context.Context, arg1, arg2 string, arg3 time.Time) (*store.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateRefreshToken", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*store.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateRefreshToken indicates an expected call of RotateRefreshToken.
+func (mr *MockRepositoryMockRecorder) RotateRefreshToken(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateRefreshToken", reflect.TypeOf((*MockRepository)(nil).RotateRefreshToken), arg0, arg1, arg2, arg3)
+}
+
+// SaveFailedLogin mocks base method.
+func (m *MockRepository) SaveFailedLogin(arg0 context.Context, arg1 *store.FailedLogin) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveFailedLogin", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveFailedLogin indicates an expected call of SaveFailedLogin.
+func (mr *MockRepositoryMockRecorder) SaveFailedLogin(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveFailedLogin", reflect.TypeOf((*MockRepository)(nil).SaveFailedLogin), arg0, arg1)
+}
+
+// SaveIdempotencyResponse mocks base method.
+func (m *MockRepository) SaveIdempotencyResponse(arg0 context.Context, arg1 int, arg2 string, arg3 int, arg4, arg5 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveIdempotencyResponse", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveIdempotencyResponse indicates an expected call of SaveIdempotencyResponse.
+func (mr *MockRepositoryMockRecorder) SaveIdempotencyResponse(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveIdempotencyResponse", reflect.TypeOf((*MockRepository)(nil).SaveIdempotencyResponse), arg0, arg1, arg2, arg3, arg4, arg5)
 }
 
 // SaveWithdrawBonuses mocks base method.
-func (m *MockRepository) SaveWithdrawBonuses(arg0 context.Context, arg1 int, arg2 string, arg3 int) error {
+func (m *MockRepository) SaveWithdrawBonuses(arg0 context.Context, arg1 int, arg2 string, arg3 money.Amount) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "SaveWithdrawBonuses", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(error)
@@ -170,11 +464,12 @@ func (mr *MockRepositoryMockRecorder) SaveWithdrawBonuses(arg0, arg1, arg2, arg3
 }
 
 // UpdateOrderStatus mocks base method.
-func (m *MockRepository) UpdateOrderStatus(arg0 context.Context, arg1 *store.Order, arg2 string, arg3 int) error {
+func (m *MockRepository) UpdateOrderStatus(arg0 context.Context, arg1 *store.Order, arg2 string, arg3 money.Amount) (bool, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateOrderStatus", arg0, arg1, arg2, arg3)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // UpdateOrderStatus indicates an expected call of UpdateOrderStatus.
@@ -183,16 +478,82 @@ func (mr *MockRepositoryMockRecorder) UpdateOrderStatus(arg0, arg1, arg2, arg3 i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrderStatus", reflect.TypeOf((*MockRepository)(nil).UpdateOrderStatus), arg0, arg1, arg2, arg3)
 }
 
-// UpdateUserToken mocks base method.
-func (m *MockRepository) UpdateUserToken(arg0 context.Context, arg1, arg2 string, arg3 time.Time) error {
+// UpdateUserPassword mocks base method.
+func (m *MockRepository) UpdateUserPassword(arg0 context.Context, arg1 int, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserPassword", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserPassword indicates an expected call of UpdateUserPassword.
+func (mr *MockRepositoryMockRecorder) UpdateUserPassword(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockRepository)(nil).UpdateUserPassword), arg0, arg1, arg2)
+}
+
+// MockAccrualProvider is a mock of AccrualProvider interface.
+type MockAccrualProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccrualProviderMockRecorder
+}
+
+// MockAccrualProviderMockRecorder is the mock recorder for MockAccrualProvider.
+type MockAccrualProviderMockRecorder struct {
+	mock *MockAccrualProvider
+}
+
+// NewMockAccrualProvider creates a new mock instance.
+func NewMockAccrualProvider(ctrl *gomock.Controller) *MockAccrualProvider {
+	mock := &MockAccrualProvider{ctrl: ctrl}
+	mock.recorder = &MockAccrualProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccrualProvider) EXPECT() *MockAccrualProviderMockRecorder {
+	return m.recorder
+}
+
+// CheckOrder mocks base method.
+func (m *MockAccrualProvider) CheckOrder(arg0 context.Context, arg1 string) (*accrual.GetOrderResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckOrder", arg0, arg1)
+	ret0, _ := ret[0].(*accrual.GetOrderResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckOrder indicates an expected call of CheckOrder.
+func (mr *MockAccrualProviderMockRecorder) CheckOrder(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckOrder", reflect.TypeOf((*MockAccrualProvider)(nil).CheckOrder), arg0, arg1)
+}
+
+// PausedFor mocks base method.
+func (m *MockAccrualProvider) PausedFor() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PausedFor")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// PausedFor indicates an expected call of PausedFor.
+func (mr *MockAccrualProviderMockRecorder) PausedFor() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PausedFor", reflect.TypeOf((*MockAccrualProvider)(nil).PausedFor))
+}
+
+// Ping mocks base method.
+func (m *MockAccrualProvider) Ping(arg0 context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUserToken", arg0, arg1, arg2, arg3)
+	ret := m.ctrl.Call(m, "Ping", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpdateUserToken indicates an expected call of UpdateUserToken.
-func (mr *MockRepositoryMockRecorder) UpdateUserToken(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+// Ping indicates an expected call of Ping.
+func (mr *MockAccrualProviderMockRecorder) Ping(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserToken", reflect.TypeOf((*MockRepository)(nil).UpdateUserToken), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockAccrualProvider)(nil).Ping), arg0)
 }
@@ -1,27 +1,59 @@
 package router
 
 import (
+	"fmt"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
+	"github.com/arseniy96/bonus-program/internal/metrics"
 	"github.com/arseniy96/bonus-program/internal/middlewares"
 	"github.com/arseniy96/bonus-program/internal/server"
+	"github.com/arseniy96/bonus-program/internal/tracing"
 )
 
-type Router interface {
-	Run(addr ...string) error
+// NewRouter возвращает обработчик публичного API. X-Forwarded-For учитывается только от адресов из
+// trusted_proxies, поэтому без настройки IP клиента — это адрес соединения.
+func NewRouter(s *server.Server) (http.Handler, error) {
+	g := gin.New()
+	if err := g.SetTrustedProxies(s.Config.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("set trusted proxies: %w", err)
+	}
+	g.Use(otelgin.Middleware(tracing.ServiceName), middlewares.RequestLogger(), middlewares.Metrics(), gin.Recovery(), middlewares.ErrorRenderer())
+	g.GET("/ping", s.PingHandler)
+	g.GET("/healthz", s.Healthz)
+	g.GET("/readyz", s.Readyz)
+
+	public := g.Group("/api/user")
+	public.POST("/register", s.SignUp)
+	public.POST("/login", s.Login)
+	public.POST("/token/refresh", s.RefreshToken)
+
+	user := g.Group("/api/user", middlewares.AuthMiddleware(s.TokenKeys))
+	user.POST("/logout", s.Logout)
+	user.GET("/sessions", s.GetSessions)
+	user.DELETE("/sessions", s.DeleteSessions)
+	user.DELETE("/sessions/:id", s.DeleteSession)
+	user.POST("/orders", middlewares.IdempotencyMiddleware(s.Repository), s.UploadOrderHandler)
+	user.GET("/orders", s.GetOrders)
+	user.GET("/balance", s.GetUserBalance)
+	user.GET("/withdrawals", s.GetUserWithdrawals)
+	user.POST("/balance/withdraw", middlewares.IdempotencyMiddleware(s.Repository), s.WithdrawHandler)
+
+	// без токена администратора административные методы не регистрируются
+	if s.Config.AdminToken != "" {
+		admin := g.Group("/api/admin", middlewares.AdminMiddleware(s.Config.AdminToken))
+		admin.GET("/orders/dead-letter", s.GetDeadLetteredOrders)
+		admin.POST("/orders/dead-letter/:number/requeue", s.RequeueOrder)
+	}
+	return g, nil
 }
 
-func NewRouter(s *server.Server) Router {
-	g := gin.Default()
-	g.Use(middlewares.AuthMiddleware(s.Repository))
-	// TODO: написать миддлварю, которая логгирует запрос/ответ
-	g.GET("/ping", s.PingHandler)
-	g.POST("/api/user/register", s.SignUp)
-	g.POST("/api/user/login", s.Login)
-	g.POST("/api/user/orders", s.UploadOrderHandler)
-	g.GET("/api/user/orders", s.GetOrders)
-	g.GET("/api/user/balance", s.GetUserBalance)
-	g.GET("/api/user/withdrawals", s.GetUserWithdrawals)
-	g.POST("/api/user/balance/withdraw", s.WithdrawHandler)
-	return g
+// NewMetricsRouter отдаёт /metrics. Он слушает отдельный внутренний адрес: метрики раскрывают внутреннее
+// состояние сервиса, и на публичном адресе их быть не должно.
+func NewMetricsRouter() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	return mux
 }
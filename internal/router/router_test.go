@@ -0,0 +1,82 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/arseniy96/bonus-program/internal/config"
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/mocks"
+	"github.com/arseniy96/bonus-program/internal/server"
+	"github.com/arseniy96/bonus-program/internal/services/authtoken"
+	"github.com/arseniy96/bonus-program/internal/services/throttle"
+	"github.com/arseniy96/bonus-program/internal/store"
+)
+
+func init() {
+	logger.Log = zap.NewNop().Sugar()
+}
+
+func TestNewRouter_LoginThrottleIgnoresSpoofedForwardedFor(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		statusCode     int
+	}{
+		// без доверенных прокси IP берётся из адреса соединения, и подмена заголовка не сбрасывает счётчик
+		{name: "untrusted peer", statusCode: http.StatusTooManyRequests},
+		// за доверенным прокси каждый запрос приходит от своего клиента
+		{name: "trusted proxy", trustedProxies: []string{"10.0.0.1"}, statusCode: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := mocks.NewMockRepository(ctrl)
+			m.EXPECT().FindUserByLogin(gomock.Any(), "nobody").Return(nil, store.ErrNowRows).AnyTimes()
+			m.EXPECT().SaveFailedLogin(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			keys, err := authtoken.NewKeys("test:test-secret-test-secret-test-secret", "")
+			require.NoError(t, err)
+			settings := &config.Settings{
+				PasswordHashCost:      bcrypt.MinCost,
+				LoginMaxFailures:      100,
+				LoginMaxFailuresPerIP: 3,
+				LoginFailureWindow:    time.Minute,
+				LoginLockout:          time.Minute,
+				LoginMaxLockout:       time.Hour,
+				TrustedProxies:        tt.trustedProxies,
+			}
+			h, err := NewRouter(server.NewServer(m, settings, keys, throttle.NewMemoryStore(), nil))
+			require.NoError(t, err)
+
+			var w *httptest.ResponseRecorder
+			for i := 0; i < settings.LoginMaxFailuresPerIP+1; i++ {
+				req := httptest.NewRequest(http.MethodPost, "/api/user/login",
+					strings.NewReader(`{"login":"nobody","password":"secret"}`))
+				req.RemoteAddr = "10.0.0.1:12345"
+				req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i+1))
+				w = httptest.NewRecorder()
+				h.ServeHTTP(w, req)
+			}
+			assert.Equal(t, tt.statusCode, w.Code)
+		})
+	}
+}
+
+func TestNewRouter_InvalidTrustedProxies(t *testing.T) {
+	s := server.NewServer(nil, &config.Settings{TrustedProxies: []string{"proxy"}}, nil, nil, nil)
+	_, err := NewRouter(s)
+	assert.Error(t, err)
+}
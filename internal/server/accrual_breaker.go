@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/metrics"
+	"github.com/arseniy96/bonus-program/internal/services/accrual"
+	"github.com/arseniy96/bonus-program/internal/services/breaker"
+)
+
+// BreakerProvider пропускает запросы к провайдеру начислений через circuit breaker. Пока breaker разомкнут,
+// CheckOrder сразу возвращает breaker.ErrOpen, а PausedFor — оставшееся время, поэтому обработчик заказов
+// не забирает новые задачи.
+type BreakerProvider struct {
+	AccrualProvider
+	Breaker *breaker.Breaker
+}
+
+// CircuitReporter — необязательный интерфейс провайдера начислений с circuit breaker. Через него Readyz
+// показывает состояние цепи, поэтому обёртки над BreakerProvider должны его пробрасывать.
+type CircuitReporter interface {
+	// CircuitState возвращает состояние breaker и сколько ещё он будет разомкнут
+	CircuitState() (breaker.State, time.Duration)
+}
+
+func NewBreakerProvider(provider AccrualProvider, b *breaker.Breaker) *BreakerProvider {
+	metrics.AccrualCircuitState.Set(float64(b.State()))
+	b.OnStateChange(func(from, to breaker.State) {
+		metrics.AccrualCircuitState.Set(float64(to))
+		metrics.AccrualCircuitTransitions.WithLabelValues(to.String()).Inc()
+		logger.Log.Warnw("accrual circuit breaker state changed", "from", from.String(), "to", to.String())
+	})
+	return &BreakerProvider{AccrualProvider: provider, Breaker: b}
+}
+
+func (p *BreakerProvider) CheckOrder(ctx context.Context, number string) (*accrual.GetOrderResponse, error) {
+	done, err := p.Breaker.Allow()
+	if err != nil {
+		return nil, err
+	}
+	res, err := p.AccrualProvider.CheckOrder(ctx, number)
+	done(isAccrualFailure(err))
+	return res, err
+}
+
+func (p *BreakerProvider) PausedFor() time.Duration {
+	pause := p.AccrualProvider.PausedFor()
+	if open := p.Breaker.OpenFor(); open > pause {
+		return open
+	}
+	return pause
+}
+
+func (p *BreakerProvider) CircuitState() (breaker.State, time.Duration) {
+	return p.Breaker.State(), p.Breaker.OpenFor()
+}
+
+// isAccrualFailure отличает недоступность системы начислений от штатных ответов: незарегистрированный
+// заказ и 429 означают, что система работает, а отмена запроса с нашей стороны ничего о ней не говорит.
+// Истёкший дедлайн — отказ: провайдер без собственного таймаута иначе никогда не разомкнёт цепь.
+func isAccrualFailure(err error) bool {
+	var tooManyErr *accrual.TooManyRequestsError
+	switch {
+	case err == nil, errors.Is(err, context.Canceled):
+		return false
+	case errors.Is(err, accrual.ErrOrderNotRegistered), errors.As(err, &tooManyErr):
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/arseniy96/bonus-program/internal/mocks"
+	"github.com/arseniy96/bonus-program/internal/services/accrual"
+	"github.com/arseniy96/bonus-program/internal/services/breaker"
+)
+
+func TestBreakerProvider_CheckOrder(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want breaker.State
+	}{
+		{
+			name: "accrual errors open the circuit",
+			ctx:  context.Background(),
+			err:  errors.New("connection refused"),
+			want: breaker.Open,
+		},
+		{
+			name: "unregistered orders are not failures",
+			ctx:  context.Background(),
+			err:  accrual.ErrOrderNotRegistered,
+			want: breaker.Closed,
+		},
+		{
+			name: "rate limiting is not a failure",
+			ctx:  context.Background(),
+			err:  &accrual.TooManyRequestsError{RetryAfter: time.Second},
+			want: breaker.Closed,
+		},
+		{
+			name: "canceled requests are not failures",
+			ctx:  canceled,
+			err:  context.Canceled,
+			want: breaker.Closed,
+		},
+		{
+			// провайдер, который завис без собственного таймаута, должен размыкать цепь
+			name: "timed out requests are failures",
+			ctx:  expired,
+			err:  context.DeadlineExceeded,
+			want: breaker.Open,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := mocks.NewMockAccrualProvider(ctrl)
+			m.EXPECT().CheckOrder(gomock.Any(), "12345678903").Return(nil, tt.err).Times(2)
+			m.EXPECT().PausedFor().Return(time.Duration(0)).AnyTimes()
+
+			p := NewBreakerProvider(m, breaker.New(breaker.Config{FailureThreshold: 2, CoolDown: time.Minute}))
+			for i := 0; i < 2; i++ {
+				_, err := p.CheckOrder(tt.ctx, "12345678903")
+				assert.ErrorIs(t, err, tt.err)
+			}
+			assert.Equal(t, tt.want, p.Breaker.State())
+
+			if tt.want == breaker.Open {
+				// провайдер больше не вызывается, а обработчик заказов ждёт, пока breaker разомкнут
+				_, err := p.CheckOrder(tt.ctx, "12345678903")
+				assert.ErrorIs(t, err, breaker.ErrOpen)
+				assert.InDelta(t, time.Minute, p.PausedFor(), float64(time.Second))
+			} else {
+				assert.Zero(t, p.PausedFor())
+			}
+		})
+	}
+}
+
+func TestBreakerProvider_PausedFor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockAccrualProvider(ctrl)
+	m.EXPECT().PausedFor().Return(time.Hour)
+
+	// пока breaker замкнут, действует пауза провайдера после ответа 429
+	p := NewBreakerProvider(m, breaker.New(breaker.Config{FailureThreshold: 1, CoolDown: time.Minute}))
+	assert.Equal(t, time.Hour, p.PausedFor())
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/arseniy96/bonus-program/internal/apierror"
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/services/validations"
+	"github.com/arseniy96/bonus-program/internal/store"
+)
+
+const (
+	deadLetterPageSize    = 100
+	deadLetterMaxPageSize = 1000
+)
+
+// GetDeadLetteredOrders возвращает заказы, у которых закончились попытки опроса системы начислений.
+// Страницы перебираются по after_id — job_id последнего заказа предыдущей страницы.
+func (s *Server) GetDeadLetteredOrders(c *gin.Context) {
+	afterID, err := strconv.Atoi(c.DefaultQuery("after_id", "0"))
+	if err != nil || afterID < 0 {
+		apierror.Abort(c, validations.Errors{{Field: "after_id", Message: "must be a non-negative integer"}})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(deadLetterPageSize)))
+	if err != nil || limit < 1 || limit > deadLetterMaxPageSize {
+		apierror.Abort(c, validations.Errors{{Field: "limit", Message: "must be between 1 and " + strconv.Itoa(deadLetterMaxPageSize)}})
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+	defer cancel()
+
+	jobs, err := s.Repository.DeadLetteredOrderJobs(ctx, afterID, limit)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("find dead lettered orders error: %v", err)
+		apierror.Abort(c, err)
+		return
+	}
+
+	response := GetDeadLetteredOrdersResponse{}
+	for _, job := range jobs {
+		response = append(response, DeadLetteredOrderResponse{
+			JobID:          job.ID,
+			Number:         job.Order.OrderNumber,
+			UserID:         job.Order.UserID,
+			Status:         job.Order.Status,
+			Attempts:       job.Attempts,
+			LastError:      job.LastError,
+			UploadedAt:     job.Order.CreatedAt.Format(time.RFC3339),
+			DeadLetteredAt: job.DeadLetteredAt.Format(time.RFC3339),
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RequeueOrder возвращает заказ из dead letter в очередь опроса с новым счётчиком попыток.
+func (s *Server) RequeueOrder(c *gin.Context) {
+	ctx, cancel := writeContext(c, 1*time.Second)
+	defer cancel()
+
+	number := c.Param("number")
+	if err := s.Repository.RequeueOrderJob(ctx, number); err != nil {
+		if errors.Is(err, store.ErrNowRows) {
+			apierror.Abort(c, apierror.Wrap(err, http.StatusNotFound, apierror.CodeNotFound, "order is not in dead letter"))
+			return
+		}
+		logger.FromContext(ctx).Errorf("requeue order error: %v", err)
+		apierror.Abort(c, err)
+		return
+	}
+
+	logger.FromContext(ctx).Infow("order requeued from dead letter", "order_number", number)
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arseniy96/bonus-program/internal/mocks"
+	"github.com/arseniy96/bonus-program/internal/store"
+)
+
+func TestServer_GetDeadLetteredOrders(t *testing.T) {
+	deadLetteredAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	job := store.OrderJob{
+		ID:             7,
+		Attempts:       20,
+		LastError:      "order is not registered in accrual system",
+		DeadLetteredAt: deadLetteredAt,
+		Order: store.Order{
+			OrderNumber: "12345678903",
+			Status:      store.OrderStatusNew,
+			UserID:      1,
+			CreatedAt:   deadLetteredAt.Add(-72 * time.Hour),
+		},
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		expect     func(m *mocks.MockRepository)
+		statusCode int
+		want       GetDeadLetteredOrdersResponse
+	}{
+		{
+			name:  "first page",
+			query: "",
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().DeadLetteredOrderJobs(gomock.Any(), 0, deadLetterPageSize).Return([]store.OrderJob{job}, nil)
+			},
+			statusCode: http.StatusOK,
+			want: GetDeadLetteredOrdersResponse{{
+				JobID:          7,
+				Number:         "12345678903",
+				UserID:         1,
+				Status:         store.OrderStatusNew,
+				Attempts:       20,
+				LastError:      "order is not registered in accrual system",
+				UploadedAt:     "2024-02-27T12:00:00Z",
+				DeadLetteredAt: "2024-03-01T12:00:00Z",
+			}},
+		},
+		{
+			name:  "empty page",
+			query: "?after_id=7&limit=10",
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().DeadLetteredOrderJobs(gomock.Any(), 7, 10).Return(nil, nil)
+			},
+			statusCode: http.StatusOK,
+			want:       GetDeadLetteredOrdersResponse{},
+		},
+		{
+			name:       "invalid limit",
+			query:      "?limit=5000",
+			expect:     func(m *mocks.MockRepository) {},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid after_id",
+			query:      "?after_id=abc",
+			expect:     func(m *mocks.MockRepository) {},
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := mocks.NewMockRepository(ctrl)
+			tt.expect(m)
+			s := &Server{Repository: m}
+			r := SetUpPublicRouter()
+			r.GET("/api/admin/orders/dead-letter", s.GetDeadLetteredOrders)
+
+			req, _ := http.NewRequest("GET", "/api/admin/orders/dead-letter"+tt.query, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			if tt.statusCode == http.StatusOK {
+				var got GetDeadLetteredOrdersResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestServer_RequeueOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+	}{
+		{
+			name:       "requeued",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "order is not in dead letter",
+			err:        store.ErrNowRows,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "database error",
+			err:        errors.New("db is down"),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := mocks.NewMockRepository(ctrl)
+			m.EXPECT().RequeueOrderJob(gomock.Any(), "12345678903").Return(tt.err)
+			s := &Server{Repository: m}
+			r := SetUpPublicRouter()
+			r.POST("/api/admin/orders/dead-letter/:number/requeue", s.RequeueOrder)
+
+			req, _ := http.NewRequest("POST", "/api/admin/orders/dead-letter/12345678903/requeue", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+		})
+	}
+}
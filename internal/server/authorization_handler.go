@@ -2,108 +2,148 @@ package server
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 
+	"github.com/arseniy96/bonus-program/internal/apierror"
 	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/services/authtoken"
 	"github.com/arseniy96/bonus-program/internal/services/mycrypto"
+	"github.com/arseniy96/bonus-program/internal/services/validations"
 	"github.com/arseniy96/bonus-program/internal/store"
 )
 
-const (
-	TokenExp = time.Hour * 24
-)
-
-type Claims struct {
-	jwt.RegisteredClaims
-	Login string
-}
+// Claims — содержимое JWT доступа. Сам тип объявлен в authtoken: токен проверяет middlewares.AuthMiddleware,
+// а пакет middlewares не может импортировать server.
+type Claims = authtoken.Claims
 
 func (s *Server) SignUp(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := writeContext(c, 3*time.Second)
 	defer cancel()
 
 	var body SignUpRequest
-	decoder := json.NewDecoder(c.Request.Body)
-	if err := decoder.Decode(&body); err != nil {
-		c.AbortWithError(http.StatusBadRequest, err)
+	if err := decodeStrictJSON(c, &body, MaxAuthBodySize); err != nil {
+		abortInvalidRequest(c, err, MaxAuthBodySize)
+		return
+	}
+	if err := validations.ValidateSignUp(body.Login, body.Password, s.passwordPolicy()); err != nil {
+		abortInvalidRequest(c, err, MaxAuthBodySize)
+		return
 	}
 
-	hPass := mycrypto.HashFunc(body.Password)
-	if err := s.Repository.CreateUser(ctx, body.Login, hPass); err != nil {
+	hPass, err := mycrypto.HashPassword(body.Password, s.Config.PasswordHashCost)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("hash password error: %v", err)
+		apierror.Abort(c, err)
+		return
+	}
+	user, err := s.Repository.CreateUser(ctx, body.Login, hPass)
+	if err != nil {
 		if errors.Is(err, store.ErrConflict) {
-			c.AbortWithError(http.StatusConflict, fmt.Errorf("user already exists"))
+			apierror.Abort(c, apierror.Wrap(err, http.StatusConflict, apierror.CodeLoginTaken, "login is already taken"))
 			return
 		}
-		logger.Log.Errorf("create user error: %v", err)
-		c.AbortWithError(http.StatusInternalServerError, err)
+		logger.FromContext(ctx).Errorf("create user error: %v", err)
+		apierror.Abort(c, err)
 		return
 	}
 
-	token, err := mycrypto.CreateRandomToken(16)
+	resp, err := startSession(ctx, s, c, user)
 	if err != nil {
-		logger.Log.Errorf("build token error: %v", err)
-		c.AbortWithError(http.StatusInternalServerError, err)
-		return
-	}
-	hashToken := mycrypto.HashFunc(token)
-	tokenExp := time.Now().Add(TokenExp)
-	if err := s.Repository.UpdateUserToken(ctx, body.Login, hashToken, tokenExp); err != nil {
-		logger.Log.Errorf("update user error: %v", err)
-		c.AbortWithError(http.StatusInternalServerError, err)
+		logger.FromContext(ctx).Errorf("start session error: %v", err)
+		apierror.Abort(c, err)
 		return
 	}
 
-	c.Header("Authorization", token)
-	c.JSON(http.StatusOK, gin.H{"login": "success"})
+	c.Header("Authorization", resp.AccessToken)
+	c.JSON(http.StatusOK, resp)
 }
 
 func (s *Server) Login(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := writeContext(c, 3*time.Second)
 	defer cancel()
 
 	var body LoginRequest
-	decoder := json.NewDecoder(c.Request.Body)
-	if err := decoder.Decode(&body); err != nil {
-		c.AbortWithError(http.StatusBadRequest, err)
+	if err := decodeStrictJSON(c, &body, MaxAuthBodySize); err != nil {
+		abortInvalidRequest(c, err, MaxAuthBodySize)
+		return
+	}
+	if err := validations.ValidateLogin(body.Login, body.Password); err != nil {
+		abortInvalidRequest(c, err, MaxAuthBodySize)
+		return
+	}
+
+	if wait := loginLockedFor(ctx, s, body.Login, c.ClientIP()); wait > 0 {
+		auditFailedLogin(ctx, s, c, body.Login, FailedLoginLocked)
+		abortTooManyAttempts(c, wait)
 		return
 	}
 
-	hPass := mycrypto.HashFunc(body.Password)
 	user, err := s.Repository.FindUserByLogin(ctx, body.Login)
 	if err != nil {
 		if errors.Is(err, store.ErrNowRows) {
-			c.AbortWithError(http.StatusUnauthorized, err)
+			// без проверки пароля ответ для несуществующего логина приходил бы заметно быстрее
+			mycrypto.CheckDummyPassword(body.Password, s.Config.PasswordHashCost)
+			if wait := loginFailed(ctx, s, c, body.Login, FailedLoginUnknownUser); wait > 0 {
+				abortTooManyAttempts(c, wait)
+				return
+			}
+			apierror.Abort(c, apierror.Wrap(err, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "invalid login or password"))
 			return
 		}
-		c.AbortWithError(http.StatusInternalServerError, err)
+		apierror.Abort(c, err)
 		return
 	}
-	if user.Password != hPass {
-		c.AbortWithError(http.StatusUnauthorized, fmt.Errorf("invalid password"))
+	ok, needsRehash := mycrypto.CheckPassword(user.Password, body.Password, s.Config.PasswordHashCost)
+	if !ok {
+		if wait := loginFailed(ctx, s, c, body.Login, FailedLoginInvalidPassword); wait > 0 {
+			abortTooManyAttempts(c, wait)
+			return
+		}
+		apierror.Abort(c, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidCredentials, "invalid login or password"))
 		return
 	}
+	loginSucceeded(ctx, s, user.Login)
+	if needsRehash {
+		// старый MD5-хэш или bcrypt с прежней стоимостью – пароль известен только сейчас, обновляем хэш
+		rehashPassword(ctx, s, user.ID, body.Password)
+	}
 
-	token, err := mycrypto.CreateRandomToken(16)
+	resp, err := startSession(ctx, s, c, user)
 	if err != nil {
-		logger.Log.Errorf("build token error: %v", err)
-		c.AbortWithError(http.StatusInternalServerError, err)
+		logger.FromContext(ctx).Errorf("start session error: %v", err)
+		apierror.Abort(c, err)
 		return
 	}
-	hashToken := mycrypto.HashFunc(token)
-	tokenExp := time.Now().Add(TokenExp)
-	if err := s.Repository.UpdateUserToken(ctx, body.Login, hashToken, tokenExp); err != nil {
-		logger.Log.Errorf("update user error: %v", err)
-		c.AbortWithError(http.StatusInternalServerError, err)
-		return
+
+	c.Header("Authorization", resp.AccessToken)
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) passwordPolicy() validations.PasswordPolicy {
+	return validations.PasswordPolicy{
+		MinLength:  s.Config.PasswordMinLength,
+		MinClasses: s.Config.PasswordMinClasses,
 	}
+}
 
-	c.Header("Authorization", token)
-	c.JSON(http.StatusOK, gin.H{"login": "success"})
+func rehashPassword(ctx context.Context, s *Server, userID int, password string) {
+	if len(password) > validations.PasswordMaxBytes {
+		// bcrypt не примет такой пароль, остаётся старый хэш – пока пользователь не сменит пароль
+		logger.FromContext(ctx).Warnw("password is too long to rehash", "user_id", userID)
+		return
+	}
+	hPass, err := mycrypto.HashPassword(password, s.Config.PasswordHashCost)
+	if err == nil {
+		err = s.Repository.UpdateUserPassword(ctx, userID, hPass)
+	}
+	if err != nil {
+		// вход от этого не должен ломаться – попробуем в следующий раз
+		logger.FromContext(ctx).Errorw("rehash password error",
+			"user_id", userID,
+			"error", err)
+	}
 }
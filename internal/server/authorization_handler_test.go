@@ -0,0 +1,215 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/arseniy96/bonus-program/internal/config"
+	"github.com/arseniy96/bonus-program/internal/mocks"
+	"github.com/arseniy96/bonus-program/internal/services/mycrypto"
+	"github.com/arseniy96/bonus-program/internal/services/throttle"
+	"github.com/arseniy96/bonus-program/internal/store"
+)
+
+func TestServer_Login_Throttling(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hash, err := mycrypto.HashPassword("secret", bcrypt.MinCost)
+	require.NoError(t, err)
+
+	m := mocks.NewMockRepository(ctrl)
+	m.EXPECT().FindUserByLogin(gomock.Any(), "gopher").
+		Return(&store.User{ID: 1, Login: "gopher", Password: hash}, nil).AnyTimes()
+	m.EXPECT().FindUserByLogin(gomock.Any(), "nobody").Return(nil, store.ErrNowRows).AnyTimes()
+	m.EXPECT().CreateSession(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	m.EXPECT().SaveFailedLogin(gomock.Any(), &store.FailedLogin{Login: "gopher", IP: "10.0.0.1", Reason: FailedLoginInvalidPassword}).
+		Return(nil).Times(3)
+	m.EXPECT().SaveFailedLogin(gomock.Any(), &store.FailedLogin{Login: "gopher", IP: "10.0.0.1", Reason: FailedLoginLocked}).
+		Return(nil).Times(1)
+	m.EXPECT().SaveFailedLogin(gomock.Any(), &store.FailedLogin{Login: "nobody", IP: "10.0.0.2", Reason: FailedLoginUnknownUser}).
+		Return(nil).Times(1)
+
+	settings := &config.Settings{
+		PasswordHashCost:      bcrypt.MinCost,
+		AccessTokenTTL:        time.Minute,
+		RefreshTokenTTL:       time.Hour,
+		LoginMaxFailures:      3,
+		LoginMaxFailuresPerIP: 100,
+		LoginFailureWindow:    time.Minute,
+		LoginLockout:          time.Minute,
+		LoginMaxLockout:       time.Hour,
+	}
+	s := NewServer(m, settings, testKeys, throttle.NewMemoryStore(), nil)
+
+	r := SetUpPublicRouter()
+	r.POST("/api/user/login", s.Login)
+
+	tests := []struct {
+		name       string
+		login      string
+		password   string
+		ip         string
+		statusCode int
+		retryAfter string
+	}{
+		{name: "success", login: "gopher", password: "secret", ip: "10.0.0.1", statusCode: http.StatusOK},
+		{name: "first failure", login: "gopher", password: "wrong", ip: "10.0.0.1", statusCode: http.StatusUnauthorized},
+		{name: "second failure", login: "gopher", password: "wrong", ip: "10.0.0.1", statusCode: http.StatusUnauthorized},
+		{name: "lockout", login: "gopher", password: "wrong", ip: "10.0.0.1", statusCode: http.StatusTooManyRequests, retryAfter: "60"},
+		{name: "correct password while locked", login: "gopher", password: "secret", ip: "10.0.0.1", statusCode: http.StatusTooManyRequests, retryAfter: "60"},
+		{name: "unknown user", login: "nobody", password: "secret", ip: "10.0.0.2", statusCode: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/api/user/login",
+				strings.NewReader(`{"login":"`+tt.login+`","password":"`+tt.password+`"}`))
+			req.RemoteAddr = tt.ip + ":12345"
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Equal(t, tt.retryAfter, w.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestServer_Login_LegacyPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		rehash   bool
+	}{
+		{name: "rehashed after login", password: "secret", rehash: true},
+		// bcrypt не принимает пароли длиннее 72 байт, такой пароль остаётся с MD5-хэшем
+		{name: "longer than bcrypt limit", password: strings.Repeat("secret", 20), rehash: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := mocks.NewMockRepository(ctrl)
+			m.EXPECT().FindUserByLogin(gomock.Any(), "gopher").
+				Return(&store.User{ID: 1, Login: "gopher", Password: mycrypto.HashFunc(tt.password)}, nil)
+			m.EXPECT().CreateSession(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+			if tt.rehash {
+				m.EXPECT().UpdateUserPassword(gomock.Any(), 1, gomock.Any()).Return(nil)
+			}
+
+			settings := &config.Settings{
+				PasswordHashCost:   bcrypt.MinCost,
+				AccessTokenTTL:     time.Minute,
+				RefreshTokenTTL:    time.Hour,
+				LoginFailureWindow: time.Minute,
+				LoginLockout:       time.Minute,
+				LoginMaxLockout:    time.Hour,
+			}
+			s := NewServer(m, settings, testKeys, throttle.NewMemoryStore(), nil)
+
+			r := SetUpPublicRouter()
+			r.POST("/api/user/login", s.Login)
+
+			req, _ := http.NewRequest("POST", "/api/user/login",
+				strings.NewReader(`{"login":"gopher","password":"`+tt.password+`"}`))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+		})
+	}
+}
+
+func TestServer_SignUp_Validation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepository(ctrl)
+	m.EXPECT().CreateUser(gomock.Any(), "gopher", gomock.Any()).Return(&store.User{ID: 1, Login: "gopher"}, nil)
+	m.EXPECT().CreateSession(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+	settings := &config.Settings{
+		PasswordHashCost:   bcrypt.MinCost,
+		PasswordMinLength:  8,
+		PasswordMinClasses: 2,
+		AccessTokenTTL:     time.Minute,
+		RefreshTokenTTL:    time.Hour,
+	}
+	s := NewServer(m, settings, testKeys, throttle.NewMemoryStore(), nil)
+
+	r := SetUpPublicRouter()
+	r.POST("/api/user/register", s.SignUp)
+
+	tests := []struct {
+		name       string
+		body       string
+		statusCode int
+		response   string
+	}{
+		{
+			name:       "success",
+			body:       `{"login":"gopher","password":"secret123"}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "invalid json",
+			body:       `{"login":`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "empty credentials",
+			body:       `{"login":"","password":""}`,
+			statusCode: http.StatusBadRequest,
+			response:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request validation failed","instance":"/api/user/register","code":"validation_failed","errors":[{"field":"login","message":"must not be empty"},{"field":"password","message":"must not be empty"}]}`,
+		},
+		{
+			name:       "weak password",
+			body:       `{"login":"gopher","password":"secretsecret"}`,
+			statusCode: http.StatusBadRequest,
+			response:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request validation failed","instance":"/api/user/register","code":"validation_failed","errors":[{"field":"password","message":"must contain at least 2 of: lowercase letters, uppercase letters, digits, other symbols"}]}`,
+		},
+		{
+			name:       "unknown field",
+			body:       `{"login":"gopher","password":"secret123","admin":true}`,
+			statusCode: http.StatusBadRequest,
+			response:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request validation failed","instance":"/api/user/register","code":"validation_failed","errors":[{"field":"admin","message":"unknown field"}]}`,
+		},
+		{
+			name:       "wrong type",
+			body:       `{"login":1,"password":"secret123"}`,
+			statusCode: http.StatusBadRequest,
+			response:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request validation failed","instance":"/api/user/register","code":"validation_failed","errors":[{"field":"login","message":"must be a string"}]}`,
+		},
+		{
+			name:       "trailing data",
+			body:       `{"login":"gopher","password":"secret123"}{}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "body too large",
+			body:       `{"login":"gopher","password":"` + strings.Repeat("a", MaxAuthBodySize) + `"}`,
+			statusCode: http.StatusRequestEntityTooLarge,
+			response:   `{"type":"about:blank","title":"Request Entity Too Large","status":413,"detail":"request body must not exceed 4096 bytes","instance":"/api/user/register","code":"request_too_large"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/api/user/register", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			if tt.response != "" {
+				assert.JSONEq(t, tt.response, w.Body.String())
+			}
+		})
+	}
+}
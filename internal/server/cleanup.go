@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/middlewares"
+	"github.com/arseniy96/bonus-program/internal/services/throttle"
+)
+
+const (
+	// CleanupInterval — как часто удаляются устаревшие служебные записи
+	CleanupInterval = time.Hour
+	cleanupTimeout  = time.Minute
+)
+
+// CleanupWorker удаляет устаревшие служебные записи сразу после запуска и затем каждые CleanupInterval,
+// пока не отменят ctx. Удаление идемпотентно, поэтому его могут выполнять все реплики одновременно.
+func (s *Server) CleanupWorker(ctx context.Context) {
+	for {
+		s.cleanup(ctx)
+		if !sleep(ctx, CleanupInterval) {
+			return
+		}
+	}
+}
+
+func (s *Server) cleanup(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, cleanupTimeout)
+	defer cancel()
+
+	deleteExpired(ctx, "idempotency keys", func(ctx context.Context) (int, error) {
+		return s.Repository.DeleteExpiredIdempotencyKeys(ctx, middlewares.IdempotencyKeyTTL)
+	})
+	deleteExpired(ctx, "failed logins", func(ctx context.Context) (int, error) {
+		return s.Repository.DeleteExpiredFailedLogins(ctx, FailedLoginRetention)
+	})
+	for _, th := range []*throttle.Throttler{s.LoginThrottle, s.IPThrottle} {
+		if th != nil {
+			deleteExpired(ctx, "login throttle records", th.DeleteExpired)
+		}
+	}
+}
+
+func deleteExpired(ctx context.Context, what string, del func(context.Context) (int, error)) {
+	deleted, err := del(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Log.Errorf("delete expired %s error: %v", what, err)
+		}
+		return
+	}
+	if deleted > 0 {
+		logger.Log.Infow("expired "+what+" deleted", "count", deleted)
+	}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arseniy96/bonus-program/internal/config"
+	"github.com/arseniy96/bonus-program/internal/middlewares"
+	"github.com/arseniy96/bonus-program/internal/mocks"
+	"github.com/arseniy96/bonus-program/internal/services/throttle"
+)
+
+func TestServer_CleanupWorker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := mocks.NewMockRepository(ctrl)
+	m.EXPECT().DeleteExpiredIdempotencyKeys(gomock.Any(), middlewares.IdempotencyKeyTTL).Return(3, nil)
+	m.EXPECT().DeleteExpiredFailedLogins(gomock.Any(), FailedLoginRetention).
+		DoAndReturn(func(context.Context, time.Duration) (int, error) {
+			cancel()
+			return 0, errors.New("connection reset")
+		})
+
+	throttleStore := throttle.NewMemoryStore()
+	s := NewServer(m, &config.Settings{
+		LoginMaxFailures:   1,
+		LoginFailureWindow: time.Nanosecond,
+		LoginLockout:       time.Nanosecond,
+		LoginMaxLockout:    time.Nanosecond,
+	}, nil, throttleStore, nil)
+	_, err := s.LoginThrottle.Fail(ctx, "gopher")
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		s.CleanupWorker(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cleanup worker did not stop")
+	}
+	// блокировка давно закончилась — запись удалена
+	deleted, err := throttleStore.DeleteExpired(context.Background(), "", time.Now(), 0, 0)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
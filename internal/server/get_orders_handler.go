@@ -7,27 +7,23 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/arseniy96/bonus-program/internal/apierror"
 	"github.com/arseniy96/bonus-program/internal/logger"
-	"github.com/arseniy96/bonus-program/internal/services/converter"
-	"github.com/arseniy96/bonus-program/internal/services/mycrypto"
+	"github.com/arseniy96/bonus-program/internal/middlewares"
 )
 
 func (s *Server) GetOrders(c *gin.Context) {
-	authHeader := c.GetHeader("Authorization")
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-	defer cancel()
-
-	token := mycrypto.HashFunc(authHeader)
-	user, err := s.Repository.FindUserByToken(ctx, token)
-	if err != nil {
-		logger.Log.Errorf("find user error: %v", err)
-		c.AbortWithError(http.StatusInternalServerError, err)
+	principal, ok := middlewares.GetPrincipal(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
 		return
 	}
-	orders, err := s.Repository.FindOrdersByUserID(ctx, user.ID)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 1*time.Second)
+	defer cancel()
+	orders, err := s.Repository.FindOrdersByUserID(ctx, principal.UserID)
 	if err != nil {
-		logger.Log.Errorf("find orders error: %v", err)
-		c.AbortWithError(http.StatusInternalServerError, err)
+		logger.FromContext(ctx).Errorf("find orders error: %v", err)
+		apierror.Abort(c, err)
 		return
 	}
 	if len(orders) == 0 {
@@ -37,18 +33,12 @@ func (s *Server) GetOrders(c *gin.Context) {
 
 	var response GetOrdersResponse
 	for _, order := range orders {
-		orderResp := OrderResponse{
+		response = append(response, OrderResponse{
 			Number:     order.OrderNumber,
 			Status:     order.Status,
+			Accrual:    order.BonusAmount, // при отсутствии начисления поле не попадает в ответ
 			UploadedAt: order.CreatedAt.Format(time.RFC3339),
-		}
-
-		ac := converter.ConvertFromCent(order.BonusAmount) // FIXME: Не знаю, как сделать по-другому
-		if ac != 0 {
-			orderResp.Accrual = ac
-		}
-
-		response = append(response, orderResp)
+		})
 	}
 	c.JSON(http.StatusOK, response)
 }
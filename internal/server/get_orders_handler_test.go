@@ -1,7 +1,6 @@
 package server
 
 import (
-	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -24,9 +23,6 @@ func TestServer_GetOrders(t *testing.T) {
 	}
 
 	m := mocks.NewMockRepository(ctrl)
-	m.EXPECT().FindUserByToken(gomock.Any(), allowedTokenHash).Return(&store.User{ID: 1}, nil)
-	m.EXPECT().FindUserByToken(gomock.Any(), allowedToken2Hash).Return(&store.User{ID: 2}, nil)
-	m.EXPECT().FindUserByToken(gomock.Any(), wrongTokenHash).Return(nil, fmt.Errorf("invalid token"))
 	m.EXPECT().FindOrdersByUserID(gomock.Any(), 1).Return([]store.Order{
 		{
 			ID:          1,
@@ -80,8 +76,8 @@ func TestServer_GetOrders(t *testing.T) {
 				AuthToken:  wrongToken,
 			},
 			want: results{
-				statusCode: http.StatusInternalServerError,
-				response:   ``,
+				statusCode: http.StatusUnauthorized,
+				response:   `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"missing or invalid access token","instance":"/api/user/orders","code":"unauthorized"}`,
 			},
 		},
 	}
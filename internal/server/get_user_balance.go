@@ -7,33 +7,36 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/arseniy96/bonus-program/internal/apierror"
 	"github.com/arseniy96/bonus-program/internal/logger"
-	"github.com/arseniy96/bonus-program/internal/services/converter"
-	"github.com/arseniy96/bonus-program/internal/services/mycrypto"
+	"github.com/arseniy96/bonus-program/internal/middlewares"
 )
 
 func (s *Server) GetUserBalance(c *gin.Context) {
-	authHeader := c.GetHeader("Authorization")
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	principal, ok := middlewares.GetPrincipal(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 1*time.Second)
 	defer cancel()
 
-	token := mycrypto.HashFunc(authHeader)
-	user, err := s.Repository.FindUserByToken(ctx, token)
+	user, err := s.Repository.FindUserByID(ctx, principal.UserID)
 	if err != nil {
-		logger.Log.Errorf("find user error: %v", err)
-		c.AbortWithError(http.StatusInternalServerError, err)
+		logger.FromContext(ctx).Errorf("find user error: %v", err)
+		apierror.Abort(c, err)
 		return
 	}
 
-	withdrawalSum, err := s.Repository.GetWithdrawalSumByUserID(ctx, user.ID)
+	withdrawalSum, err := s.Repository.GetWithdrawalSumByUserID(ctx, principal.UserID)
 	if err != nil {
-		logger.Log.Errorf("find bonus_transactions error: %v", err)
-		c.AbortWithError(http.StatusInternalServerError, err)
+		logger.FromContext(ctx).Errorf("find bonus_transactions error: %v", err)
+		apierror.Abort(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, GetUserBalanceResponse{
-		Current:   converter.ConvertFromCent(user.Bonuses),  // в БД храним в копейках
-		Withdrawn: converter.ConvertFromCent(withdrawalSum), // в БД храним в копейках
+		Current:   user.Bonuses,
+		Withdrawn: withdrawalSum,
 	})
 }
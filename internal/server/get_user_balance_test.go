@@ -1,7 +1,6 @@
 package server
 
 import (
-	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/arseniy96/bonus-program/internal/mocks"
+	"github.com/arseniy96/bonus-program/internal/services/money"
 	"github.com/arseniy96/bonus-program/internal/store"
 )
 
@@ -19,9 +19,8 @@ func TestServer_GetUserBalance(t *testing.T) {
 	defer ctrl.Finish()
 
 	m := mocks.NewMockRepository(ctrl)
-	m.EXPECT().FindUserByToken(gomock.Any(), allowedTokenHash).Return(&store.User{ID: 1, Bonuses: 100}, nil)
-	m.EXPECT().FindUserByToken(gomock.Any(), wrongTokenHash).Return(nil, fmt.Errorf("invalid token"))
-	m.EXPECT().GetWithdrawalSumByUserID(gomock.Any(), 1).Return(500, nil)
+	m.EXPECT().FindUserByID(gomock.Any(), 1).Return(&store.User{ID: 1, Bonuses: 100}, nil)
+	m.EXPECT().GetWithdrawalSumByUserID(gomock.Any(), 1).Return(money.Amount(500), nil)
 
 	type fields struct {
 		Repository Repository
@@ -54,8 +53,8 @@ func TestServer_GetUserBalance(t *testing.T) {
 				AuthToken:  wrongToken,
 			},
 			want: results{
-				statusCode: http.StatusInternalServerError,
-				response:   ``,
+				statusCode: http.StatusUnauthorized,
+				response:   `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"missing or invalid access token","instance":"/api/user/balance","code":"unauthorized"}`,
 			},
 		},
 	}
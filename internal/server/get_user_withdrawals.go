@@ -7,29 +7,25 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/arseniy96/bonus-program/internal/apierror"
 	"github.com/arseniy96/bonus-program/internal/logger"
-	"github.com/arseniy96/bonus-program/internal/services/converter"
-	"github.com/arseniy96/bonus-program/internal/services/mycrypto"
+	"github.com/arseniy96/bonus-program/internal/middlewares"
 	"github.com/arseniy96/bonus-program/internal/store"
 )
 
 func (s *Server) GetUserWithdrawals(c *gin.Context) {
-	authHeader := c.GetHeader("Authorization")
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-	defer cancel()
-
-	token := mycrypto.HashFunc(authHeader)
-	user, err := s.Repository.FindUserByToken(ctx, token)
-	if err != nil {
-		logger.Log.Errorf("find user error: %v", err)
-		c.AbortWithError(http.StatusInternalServerError, err)
+	principal, ok := middlewares.GetPrincipal(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
 		return
 	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 1*time.Second)
+	defer cancel()
 
-	bonusTransactions, err := s.Repository.FindBonusTransactionsByUserID(ctx, user.ID)
+	bonusTransactions, err := s.Repository.FindBonusTransactionsByUserID(ctx, principal.UserID)
 	if err != nil {
-		logger.Log.Errorf("find bonus_transactions error: %v", err)
-		c.AbortWithError(http.StatusInternalServerError, err)
+		logger.FromContext(ctx).Errorf("find bonus_transactions error: %v", err)
+		apierror.Abort(c, err)
 		return
 	}
 	var response GetUserWithdrawalsResponse
@@ -37,7 +33,7 @@ func (s *Server) GetUserWithdrawals(c *gin.Context) {
 		if tr.Type == store.WithdrawalType {
 			response = append(response, WithdrawalsResponse{
 				Order:       tr.OrderNumber,
-				Sum:         converter.ConvertFromCent(tr.Amount),
+				Sum:         tr.Amount,
 				ProcessedAt: tr.CreatedAt.Format(time.RFC3339),
 			})
 		}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/services/breaker"
+)
+
+const (
+	// ReadinessTimeout — сколько ждём ответа от всех зависимостей в Readyz
+	ReadinessTimeout = 2 * time.Second
+	// WorkerHeartbeatTimeout — через сколько без heartbeat обработчик заказов считается зависшим.
+	// Цикл раздачи задач может ждать свободного обработчика, поэтому берём с запасом от JobLease.
+	WorkerHeartbeatTimeout = 2 * JobLease
+
+	HealthStatusOK   = "ok"
+	HealthStatusFail = "fail"
+	// HealthStatusDegraded — недоступны только необязательные зависимости, сервис продолжает принимать запросы
+	HealthStatusDegraded = "degraded"
+)
+
+type readinessCheck struct {
+	name string
+	// required — без этой зависимости сервис не может обслуживать запросы
+	required bool
+	// failure — что отдаём клиенту при ошибке; подробности пишутся только в лог, т.к. /readyz доступен без авторизации
+	failure string
+	check   func(ctx context.Context) (map[string]interface{}, error)
+}
+
+// Healthz отвечает, что процесс жив. Зависимости не проверяются, чтобы их недоступность
+// не приводила к перезапуску сервиса.
+func (s *Server) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{Status: HealthStatusOK})
+}
+
+// Readyz проверяет базу, версию схемы, систему начислений и обработчик заказов. Если недоступна обязательная
+// зависимость, отвечает 503. Система начислений необязательна: пока она лежит, заказы принимаются и ждут
+// в очереди, поэтому её недоступность (в том числе разомкнутый circuit breaker) переводит сервис только
+// в статус degraded.
+func (s *Server) Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ReadinessTimeout)
+	defer cancel()
+
+	checks := []readinessCheck{
+		{name: "database", required: true, failure: "database is unavailable", check: s.checkDatabase},
+		{name: "migrations", required: true, failure: "database schema is not up to date", check: s.checkMigrations},
+		{name: "accrual", required: false, failure: "accrual system is unavailable", check: s.checkAccrual},
+		{name: "worker", required: true, failure: "orders worker is not running", check: s.checkWorker},
+	}
+
+	results := make(map[string]HealthCheck, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, rc := range checks {
+		wg.Add(1)
+		go func(rc readinessCheck) {
+			defer wg.Done()
+			result := runCheck(ctx, rc)
+			mu.Lock()
+			results[rc.name] = result
+			mu.Unlock()
+		}(rc)
+	}
+	wg.Wait()
+
+	status := HealthStatusOK
+	for _, rc := range checks {
+		if results[rc.name].Status == HealthStatusOK {
+			continue
+		}
+		if rc.required {
+			status = HealthStatusFail
+		} else if status == HealthStatusOK {
+			status = HealthStatusDegraded
+		}
+	}
+
+	code := http.StatusOK
+	if status == HealthStatusFail {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, HealthResponse{Status: status, Checks: results})
+}
+
+func runCheck(ctx context.Context, rc readinessCheck) HealthCheck {
+	start := time.Now()
+	details, err := rc.check(ctx)
+	result := HealthCheck{
+		Status:    HealthStatusOK,
+		LatencyMS: time.Since(start).Milliseconds(),
+		Details:   details,
+	}
+	if err != nil {
+		logger.FromContext(ctx).Warnw("readiness check failed", "check", rc.name, "error", err)
+		result.Status = HealthStatusFail
+		result.Error = rc.failure
+	}
+	return result
+}
+
+func (s *Server) checkDatabase(ctx context.Context) (map[string]interface{}, error) {
+	return nil, s.Repository.Ping(ctx)
+}
+
+func (s *Server) checkMigrations(ctx context.Context) (map[string]interface{}, error) {
+	status, err := s.Repository.MigrationStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]interface{}{
+		"version":  status.Version,
+		"expected": status.Expected,
+	}
+	switch {
+	case status.Dirty:
+		return details, fmt.Errorf("migration %d is dirty", status.Version)
+	case status.Version < status.Expected:
+		return details, errors.New("database schema is older than expected")
+	}
+	return details, nil
+}
+
+func (s *Server) checkAccrual(ctx context.Context) (map[string]interface{}, error) {
+	cr, ok := s.Accrual.(CircuitReporter)
+	if !ok {
+		return nil, s.Accrual.Ping(ctx)
+	}
+
+	state, open := cr.CircuitState()
+	details := map[string]interface{}{"circuit": state.String()}
+	if open > 0 {
+		details["circuit_open_seconds"] = int(open.Seconds())
+		return details, breaker.ErrOpen
+	}
+	return details, s.Accrual.Ping(ctx)
+}
+
+func (s *Server) checkWorker(context.Context) (map[string]interface{}, error) {
+	last := s.lastHeartbeat()
+	if last.IsZero() {
+		return nil, errors.New("orders worker has not started")
+	}
+
+	age := time.Since(last)
+	details := map[string]interface{}{
+		"last_heartbeat": last.UTC().Format(time.RFC3339),
+		"age_seconds":    int(age.Seconds()),
+	}
+	if age > WorkerHeartbeatTimeout {
+		return details, fmt.Errorf("no heartbeat for %v", age.Truncate(time.Second))
+	}
+	return details, nil
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arseniy96/bonus-program/internal/mocks"
+	"github.com/arseniy96/bonus-program/internal/services/accrual"
+	"github.com/arseniy96/bonus-program/internal/services/breaker"
+	"github.com/arseniy96/bonus-program/internal/store"
+)
+
+func TestServer_Healthz(t *testing.T) {
+	s := &Server{}
+	r := SetUpPublicRouter()
+	r.GET("/healthz", s.Healthz)
+
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestServer_Readyz(t *testing.T) {
+	accrualUp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer accrualUp.Close()
+	accrualDown := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer accrualDown.Close()
+
+	migrated := &store.MigrationStatus{Version: 14, Expected: 14}
+
+	type fields struct {
+		pingErr     error
+		migrations  *store.MigrationStatus
+		accrualHost string
+		circuitOpen bool
+		heartbeat   time.Duration
+	}
+	type results struct {
+		statusCode int
+		status     string
+		checks     map[string]string
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		want   results
+	}{
+		{
+			name: "all dependencies are ready",
+			fields: fields{
+				migrations:  migrated,
+				accrualHost: accrualUp.URL,
+				heartbeat:   time.Second,
+			},
+			want: results{
+				statusCode: http.StatusOK,
+				status:     HealthStatusOK,
+				checks:     map[string]string{"database": "ok", "migrations": "ok", "accrual": "ok", "worker": "ok"},
+			},
+		},
+		{
+			name: "schema is newer than expected",
+			fields: fields{
+				migrations:  &store.MigrationStatus{Version: 15, Expected: 14},
+				accrualHost: accrualUp.URL,
+				heartbeat:   time.Second,
+			},
+			want: results{
+				statusCode: http.StatusOK,
+				status:     HealthStatusOK,
+				checks:     map[string]string{"database": "ok", "migrations": "ok", "accrual": "ok", "worker": "ok"},
+			},
+		},
+		{
+			name: "accrual is unavailable",
+			fields: fields{
+				migrations:  migrated,
+				accrualHost: accrualDown.URL,
+				heartbeat:   time.Second,
+			},
+			want: results{
+				statusCode: http.StatusOK,
+				status:     HealthStatusDegraded,
+				checks:     map[string]string{"database": "ok", "migrations": "ok", "accrual": "fail", "worker": "ok"},
+			},
+		},
+		{
+			name: "accrual circuit is open",
+			fields: fields{
+				migrations:  migrated,
+				accrualHost: accrualUp.URL,
+				circuitOpen: true,
+				heartbeat:   time.Second,
+			},
+			want: results{
+				statusCode: http.StatusOK,
+				status:     HealthStatusDegraded,
+				checks:     map[string]string{"database": "ok", "migrations": "ok", "accrual": "fail", "worker": "ok"},
+			},
+		},
+		{
+			name: "database is unavailable",
+			fields: fields{
+				pingErr:     errors.New("connection refused"),
+				migrations:  migrated,
+				accrualHost: accrualUp.URL,
+				heartbeat:   time.Second,
+			},
+			want: results{
+				statusCode: http.StatusServiceUnavailable,
+				status:     HealthStatusFail,
+				checks:     map[string]string{"database": "fail", "migrations": "ok", "accrual": "ok", "worker": "ok"},
+			},
+		},
+		{
+			name: "dirty migration",
+			fields: fields{
+				migrations:  &store.MigrationStatus{Version: 14, Dirty: true, Expected: 14},
+				accrualHost: accrualUp.URL,
+				heartbeat:   time.Second,
+			},
+			want: results{
+				statusCode: http.StatusServiceUnavailable,
+				status:     HealthStatusFail,
+				checks:     map[string]string{"database": "ok", "migrations": "fail", "accrual": "ok", "worker": "ok"},
+			},
+		},
+		{
+			name: "schema is outdated",
+			fields: fields{
+				migrations:  &store.MigrationStatus{Version: 13, Expected: 14},
+				accrualHost: accrualUp.URL,
+				heartbeat:   time.Second,
+			},
+			want: results{
+				statusCode: http.StatusServiceUnavailable,
+				status:     HealthStatusFail,
+				checks:     map[string]string{"database": "ok", "migrations": "fail", "accrual": "ok", "worker": "ok"},
+			},
+		},
+		{
+			name: "worker heartbeat is stale",
+			fields: fields{
+				migrations:  migrated,
+				accrualHost: accrualUp.URL,
+				heartbeat:   WorkerHeartbeatTimeout + time.Minute,
+			},
+			want: results{
+				statusCode: http.StatusServiceUnavailable,
+				status:     HealthStatusFail,
+				checks:     map[string]string{"database": "ok", "migrations": "ok", "accrual": "ok", "worker": "fail"},
+			},
+		},
+		{
+			name: "worker has not started",
+			fields: fields{
+				migrations:  migrated,
+				accrualHost: accrualUp.URL,
+			},
+			want: results{
+				statusCode: http.StatusServiceUnavailable,
+				status:     HealthStatusFail,
+				checks:     map[string]string{"database": "ok", "migrations": "ok", "accrual": "ok", "worker": "fail"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := mocks.NewMockRepository(ctrl)
+			m.EXPECT().Ping(gomock.Any()).Return(tt.fields.pingErr)
+			m.EXPECT().MigrationStatus(gomock.Any()).Return(tt.fields.migrations, nil)
+
+			s := &Server{
+				Repository: m,
+				Accrual:    accrual.NewClient(tt.fields.accrualHost),
+			}
+			if tt.fields.circuitOpen {
+				b := breaker.New(breaker.Config{FailureThreshold: 1, CoolDown: time.Minute})
+				done, err := b.Allow()
+				require.NoError(t, err)
+				done(true)
+				s.Accrual = NewBreakerProvider(s.Accrual, b)
+			}
+			if tt.fields.heartbeat > 0 {
+				s.workerHeartbeat.Store(time.Now().Add(-tt.fields.heartbeat).UnixNano())
+			}
+
+			r := SetUpPublicRouter()
+			r.GET("/readyz", s.Readyz)
+			req, _ := http.NewRequest("GET", "/readyz", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want.statusCode, w.Code)
+			var resp HealthResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tt.want.status, resp.Status)
+			checks := make(map[string]string, len(resp.Checks))
+			for name, check := range resp.Checks {
+				checks[name] = check.Status
+				if check.Status == HealthStatusFail {
+					assert.NotEmpty(t, check.Error, name)
+				}
+				// текст ошибки зависимости наружу не отдаётся
+				assert.NotContains(t, check.Error, "connection refused", name)
+			}
+			assert.Equal(t, tt.want.checks, checks)
+			if tt.fields.circuitOpen {
+				assert.Equal(t, "open", resp.Checks["accrual"].Details["circuit"])
+			}
+		})
+	}
+}
+
+// circuitProvider — провайдер с собственным circuit breaker, не связанный с BreakerProvider
+type circuitProvider struct {
+	AccrualProvider
+	state   breaker.State
+	openFor time.Duration
+}
+
+func (p circuitProvider) CircuitState() (breaker.State, time.Duration) {
+	return p.state, p.openFor
+}
+
+func TestServer_checkAccrual_CircuitReporter(t *testing.T) {
+	s := &Server{Accrual: circuitProvider{state: breaker.Open, openFor: 30 * time.Second}}
+
+	details, err := s.checkAccrual(context.Background())
+	assert.ErrorIs(t, err, breaker.ErrOpen)
+	assert.Equal(t, map[string]interface{}{"circuit": "open", "circuit_open_seconds": 30}, details)
+}
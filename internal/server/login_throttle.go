@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/arseniy96/bonus-program/internal/apierror"
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/services/throttle"
+	"github.com/arseniy96/bonus-program/internal/store"
+)
+
+// Причины неудачного входа в аудите.
+const (
+	FailedLoginUnknownUser     = "unknown_user"
+	FailedLoginInvalidPassword = "invalid_password"
+	FailedLoginLocked          = "locked"
+)
+
+// FailedLoginRetention — сколько хранятся записи аудита неудачных входов
+const FailedLoginRetention = 30 * 24 * time.Hour
+
+type throttleKey struct {
+	throttler *throttle.Throttler
+	key       string
+}
+
+func loginThrottleKeys(s *Server, login, ip string) []throttleKey {
+	return []throttleKey{
+		{throttler: s.LoginThrottle, key: login},
+		{throttler: s.IPThrottle, key: ip},
+	}
+}
+
+// loginLockedFor возвращает, сколько ещё заблокирован вход для логина или IP клиента.
+// Ошибки хранилища не должны мешать входу, поэтому они только логируются.
+func loginLockedFor(ctx context.Context, s *Server, login, ip string) time.Duration {
+	var wait time.Duration
+	for _, k := range loginThrottleKeys(s, login, ip) {
+		left, err := k.throttler.Check(ctx, k.key)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("check login throttle error: %v", err)
+			continue
+		}
+		if left > wait {
+			wait = left
+		}
+	}
+	return wait
+}
+
+// loginFailed записывает неудачную попытку в аудит и учитывает её в счётчиках. Возвращает длительность
+// блокировки, если попытка её вызвала.
+func loginFailed(ctx context.Context, s *Server, c *gin.Context, login, reason string) time.Duration {
+	ip := c.ClientIP()
+	auditFailedLogin(ctx, s, c, login, reason)
+
+	var wait time.Duration
+	for _, k := range loginThrottleKeys(s, login, ip) {
+		lock, err := k.throttler.Fail(ctx, k.key)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("register failed login error: %v", err)
+			continue
+		}
+		if lock > wait {
+			wait = lock
+		}
+	}
+	if wait > 0 {
+		logger.FromContext(ctx).Warnw("login locked after failed attempts",
+			"login", login,
+			"ip", ip,
+			"lockout", wait)
+	}
+	return wait
+}
+
+func loginSucceeded(ctx context.Context, s *Server, login string) {
+	// счётчик по IP не сбрасываем: иначе перебор чужих паролей можно перемежать входом в свой аккаунт
+	if err := s.LoginThrottle.Reset(ctx, login); err != nil {
+		logger.FromContext(ctx).Errorf("reset login throttle error: %v", err)
+	}
+}
+
+func auditFailedLogin(ctx context.Context, s *Server, c *gin.Context, login, reason string) {
+	err := s.Repository.SaveFailedLogin(ctx, &store.FailedLogin{
+		Login:     login,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Reason:    reason,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Errorf("save failed login error: %v", err)
+	}
+}
+
+func abortTooManyAttempts(c *gin.Context, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	apierror.Abort(c, apierror.New(http.StatusTooManyRequests, apierror.CodeTooManyLoginAttempts, "too many failed login attempts, try again later"))
+}
@@ -1,21 +1,53 @@
 package server
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 
-	"github.com/arseniy96/bonus-program/internal/services/mycrypto"
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/middlewares"
+	"github.com/arseniy96/bonus-program/internal/services/authtoken"
 )
 
+func init() {
+	logger.Log = zap.NewNop().Sugar()
+}
+
 func SetUpRouter() *gin.Engine {
+	router := SetUpPublicRouter()
+	router.Use(middlewares.AuthMiddleware(testKeys))
+	return router
+}
+
+func SetUpPublicRouter() *gin.Engine {
 	router := gin.Default()
+	router.Use(middlewares.ErrorRenderer())
 	return router
 }
 
 var (
-	allowedToken      = "some_auth_token"
-	allowedToken2     = "some_auth_token_zero_orders"
-	wrongToken        = "wrong_auth_token"
-	allowedTokenHash  = mycrypto.HashFunc(allowedToken)
-	allowedToken2Hash = mycrypto.HashFunc(allowedToken2)
-	wrongTokenHash    = mycrypto.HashFunc(wrongToken)
+	testKeys      = mustKeys("test:test-secret-test-secret-test-secret")
+	allowedToken  = mustIssue(1, "gopher")
+	allowedToken2 = mustIssue(2, "gopher_zero_orders")
+	wrongToken    = "wrong_auth_token"
 )
+
+const testSessionID = "current_session"
+
+func mustKeys(spec string) *authtoken.Keys {
+	keys, err := authtoken.NewKeys(spec, "")
+	if err != nil {
+		panic(err)
+	}
+	return keys
+}
+
+func mustIssue(userID int, login string) string {
+	token, err := testKeys.Issue(Claims{UserID: userID, Login: login, SessionID: testSessionID}, time.Hour)
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
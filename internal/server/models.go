@@ -1,5 +1,7 @@
 package server
 
+import "github.com/arseniy96/bonus-program/internal/services/money"
+
 type SignUpRequest struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
@@ -10,29 +12,79 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// AuthResponse возвращается при регистрации, входе и обновлении токена. Токен доступа, как и раньше,
+// передаётся ещё и в заголовке Authorization.
+type AuthResponse struct {
+	Login        string `json:"login"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type GetSessionsResponse []SessionResponse
+
+type SessionResponse struct {
+	ID         string `json:"id"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at"`
+	Current    bool   `json:"current"`
+}
+
+type GetDeadLetteredOrdersResponse []DeadLetteredOrderResponse
+
+type DeadLetteredOrderResponse struct {
+	JobID          int    `json:"job_id"`
+	Number         string `json:"number"`
+	UserID         int    `json:"user_id"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	LastError      string `json:"last_error,omitempty"`
+	UploadedAt     string `json:"uploaded_at"`
+	DeadLetteredAt string `json:"dead_lettered_at"`
+}
+
 type GetOrdersResponse []OrderResponse
 
 type OrderResponse struct {
-	Number     string  `json:"number"`
-	Status     string  `json:"status"`
-	Accrual    float64 `json:"accrual,omitempty"`
-	UploadedAt string  `json:"uploaded_at"`
+	Number     string       `json:"number"`
+	Status     string       `json:"status"`
+	Accrual    money.Amount `json:"accrual,omitempty"`
+	UploadedAt string       `json:"uploaded_at"`
 }
 
 type GetUserBalanceResponse struct {
-	Current   float64 `json:"current"`
-	Withdrawn float64 `json:"withdrawn"`
+	Current   money.Amount `json:"current"`
+	Withdrawn money.Amount `json:"withdrawn"`
 }
 
 type GetUserWithdrawalsResponse []WithdrawalsResponse
 
 type WithdrawalsResponse struct {
-	Order       string  `json:"order"`
-	Sum         float64 `json:"sum"`
-	ProcessedAt string  `json:"processed_at"`
+	Order       string       `json:"order"`
+	Sum         money.Amount `json:"sum"`
+	ProcessedAt string       `json:"processed_at"`
 }
 
 type WithdrawRequest struct {
-	Order string  `json:"order"`
-	Sum   float64 `json:"sum"`
+	Order string       `json:"order"`
+	Sum   money.Amount `json:"sum"`
+}
+
+// HealthResponse возвращается из /healthz и /readyz; Checks заполняется только для /readyz.
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+type HealthCheck struct {
+	Status    string                 `json:"status"`
+	LatencyMS int64                  `json:"latency_ms"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
 }
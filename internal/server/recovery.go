@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/arseniy96/bonus-program/internal/logger"
+)
+
+const RecoveryPageSize = 1000
+
+// RecoverOrders возвращает в обработку заказы в статусах NEW и PROCESSING, для которых нет задачи на опрос
+// системы начислений (например, созданные до появления очереди задач).
+func (s *Server) RecoverOrders(ctx context.Context) error {
+	started := time.Now()
+	var lastID, scanned, enqueued, pages int
+
+	for {
+		pageCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+		page, err := s.Repository.RecoverOrderJobs(pageCtx, lastID, RecoveryPageSize)
+		cancel()
+		if err != nil {
+			logger.Log.Errorw("orders recovery failed",
+				"last_order_id", lastID,
+				"scanned", scanned,
+				"enqueued", enqueued)
+			return err
+		}
+
+		pages++
+		scanned += page.Scanned
+		enqueued += page.Enqueued
+		if page.Scanned < RecoveryPageSize {
+			break
+		}
+		lastID = page.LastID
+	}
+
+	logger.Log.Infow("orders recovery finished",
+		"pages", pages,
+		"scanned", scanned,
+		"enqueued", enqueued,
+		"duration", time.Since(started))
+	return nil
+}
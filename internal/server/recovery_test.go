@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/arseniy96/bonus-program/internal/mocks"
+	"github.com/arseniy96/bonus-program/internal/store"
+)
+
+func TestServer_RecoverOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("walks through all pages", func(t *testing.T) {
+		m := mocks.NewMockRepository(ctrl)
+		gomock.InOrder(
+			m.EXPECT().RecoverOrderJobs(gomock.Any(), 0, RecoveryPageSize).
+				Return(&store.RecoveryPage{LastID: 1500, Scanned: RecoveryPageSize, Enqueued: 10}, nil),
+			m.EXPECT().RecoverOrderJobs(gomock.Any(), 1500, RecoveryPageSize).
+				Return(&store.RecoveryPage{LastID: 1700, Scanned: 42, Enqueued: 2}, nil),
+		)
+
+		s := &Server{Repository: m}
+		assert.NoError(t, s.RecoverOrders(context.Background()))
+	})
+
+	t.Run("stops on error", func(t *testing.T) {
+		m := mocks.NewMockRepository(ctrl)
+		m.EXPECT().RecoverOrderJobs(gomock.Any(), 0, RecoveryPageSize).Return(nil, fmt.Errorf("db is down"))
+
+		s := &Server{Repository: m}
+		assert.Error(t, s.RecoverOrders(context.Background()))
+	})
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/arseniy96/bonus-program/internal/apierror"
+	"github.com/arseniy96/bonus-program/internal/services/ctxutil"
+	"github.com/arseniy96/bonus-program/internal/services/validations"
+)
+
+// MaxAuthBodySize — ограничение на размер тела запросов регистрации и входа.
+const MaxAuthBodySize = 4 << 10
+
+// writeContext возвращает контекст для обработчика, который пишет в базу. Отключение клиента не должно
+// прерывать запись на полпути (списание, выпуск токенов), поэтому контекст ограничен только timeout.
+func writeContext(c *gin.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctxutil.WithoutCancel(c.Request.Context()), timeout)
+}
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// decodeStrictJSON читает тело не больше maxBytes и разбирает его в v, отвергая неизвестные поля и
+// данные после JSON-объекта. Ошибки, относящиеся к конкретным полям, возвращаются как validations.Errors.
+func decodeStrictJSON(c *gin.Context, v any, maxBytes int64) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return decodeError(err)
+		}
+		return errors.New("request body must contain a single JSON object")
+	}
+	return nil
+}
+
+func decodeError(err error) error {
+	var (
+		maxBytesErr *http.MaxBytesError
+		typeErr     *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return errBodyTooLarge
+	case errors.As(err, &typeErr):
+		return validations.Errors{{Field: typeErr.Field, Message: fmt.Sprintf("must be a %s", typeErr.Type)}}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return validations.Errors{{Field: field, Message: "unknown field"}}
+	}
+	return err
+}
+
+// abortInvalidRequest отвечает клиенту на ошибку разбора или проверки запроса. maxBytes — ограничение,
+// с которым разбиралось тело; оно называется клиенту, если тело его превысило.
+func abortInvalidRequest(c *gin.Context, err error, maxBytes int64) {
+	var fieldErrs validations.Errors
+	switch {
+	case errors.Is(err, errBodyTooLarge):
+		apierror.Abort(c, apierror.Wrap(err, http.StatusRequestEntityTooLarge, apierror.CodeRequestTooLarge,
+			fmt.Sprintf("request body must not exceed %d bytes", maxBytes)))
+	case errors.As(err, &fieldErrs):
+		apierror.Abort(c, err)
+	default:
+		apierror.Abort(c, apierror.Wrap(err, http.StatusBadRequest, apierror.CodeBadRequest, "malformed JSON body"))
+	}
+}
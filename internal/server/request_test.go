@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAbortInvalidRequest_BodyTooLarge(t *testing.T) {
+	r := SetUpPublicRouter()
+	r.POST("/test", func(c *gin.Context) {
+		var body map[string]string
+		if err := decodeStrictJSON(c, &body, 16); err != nil {
+			abortInvalidRequest(c, err, 16)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	req, _ := http.NewRequest("POST", "/test", strings.NewReader(`{"key":"long value"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// в ответе ограничение этого обработчика, а не MaxAuthBodySize
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), `"detail":"request body must not exceed 16 bytes"`)
+}
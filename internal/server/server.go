@@ -2,40 +2,94 @@ package server
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	"github.com/arseniy96/bonus-program/internal/config"
+	"github.com/arseniy96/bonus-program/internal/services/accrual"
+	"github.com/arseniy96/bonus-program/internal/services/authtoken"
+	"github.com/arseniy96/bonus-program/internal/services/money"
+	"github.com/arseniy96/bonus-program/internal/services/throttle"
 	"github.com/arseniy96/bonus-program/internal/store"
 )
 
 type Server struct {
-	Repository  Repository
-	Config      *config.Settings
-	OrdersQueue chan *store.Order
+	Repository Repository
+	Config     *config.Settings
+	Accrual    AccrualProvider
+	TokenKeys  *authtoken.Keys
+	// LoginThrottle и IPThrottle ограничивают неудачные попытки входа по логину и по IP клиента
+	LoginThrottle *throttle.Throttler
+	IPThrottle    *throttle.Throttler
+	// workerHeartbeat — время (UnixNano) последнего прохода цикла раздачи задач, см. Readyz
+	workerHeartbeat atomic.Int64
 }
 
 type Repository interface {
-	CreateUser(context.Context, string, string) error
-	UpdateUserToken(context.Context, string, string, time.Time) error
+	CreateUser(context.Context, string, string) (*store.User, error)
+	UpdateUserPassword(context.Context, int, string) error
 	FindUserByLogin(context.Context, string) (*store.User, error)
-	FindUserByToken(context.Context, string) (*store.User, error)
+	FindUserByID(context.Context, int) (*store.User, error)
 	FindOrdersByUserID(context.Context, int) ([]store.Order, error)
 	FindBonusTransactionsByUserID(context.Context, int) ([]store.BonusTransaction, error)
-	GetWithdrawalSumByUserID(context.Context, int) (int, error)
-	SaveWithdrawBonuses(context.Context, int, string, int) error
+	GetWithdrawalSumByUserID(context.Context, int) (money.Amount, error)
+	SaveWithdrawBonuses(context.Context, int, string, money.Amount) error
 	FindOrderByOrderNumber(context.Context, string) (*store.Order, error)
 	CreateOrder(context.Context, int, string, string) (*store.Order, error)
-	UpdateOrderStatus(context.Context, *store.Order, string, int) error
+	// UpdateOrderStatus возвращает false, если заказ уже был финализирован и ничего не изменилось
+	UpdateOrderStatus(context.Context, *store.Order, string, money.Amount) (bool, error)
+	ClaimOrderJobs(context.Context, int, time.Duration) ([]store.OrderJob, error)
+	RescheduleOrderJob(context.Context, int, time.Duration, string) error
+	ReleaseOrderJob(context.Context, int, time.Duration, string) error
+	DeadLetterOrderJob(context.Context, int, string) error
+	DeadLetteredOrderJobs(context.Context, int, int) ([]store.OrderJob, error)
+	RequeueOrderJob(context.Context, string) error
+	RecoverOrderJobs(context.Context, int, int) (*store.RecoveryPage, error)
+	ReserveIdempotencyKey(context.Context, int, string, string, time.Duration) (*store.IdempotencyKey, bool, error)
+	SaveIdempotencyResponse(context.Context, int, string, int, string, string) error
+	DeleteIdempotencyKey(context.Context, int, string) error
+	DeleteExpiredIdempotencyKeys(context.Context, time.Duration) (int, error)
+	CreateSession(context.Context, *store.Session, string) error
+	RotateRefreshToken(context.Context, string, string, time.Time) (*store.Session, error)
+	FindActiveSessionsByUserID(context.Context, int) ([]store.Session, error)
+	RevokeSession(context.Context, int, string) error
+	RevokeOtherSessions(context.Context, int, string) (int, error)
+	SaveFailedLogin(context.Context, *store.FailedLogin) error
+	DeleteExpiredFailedLogins(context.Context, time.Duration) (int, error)
+	Ping(context.Context) error
+	MigrationStatus(context.Context) (*store.MigrationStatus, error)
 }
 
-func NewServer(r Repository, c *config.Settings) *Server {
-	server := &Server{
-		Repository:  r,
-		Config:      c,
-		OrdersQueue: make(chan *store.Order, 10),
-	}
-
-	go server.OrdersWorker()
+// AccrualProvider сообщает результат расчёта начислений по заказу. Основная реализация — HTTP-клиент
+// внешней системы начислений (accrual.Client), для разработки и тестов есть accrualengine.RulesProvider
+// и accrualstub.FixtureProvider.
+type AccrualProvider interface {
+	// CheckOrder возвращает accrual.ErrOrderNotRegistered, если провайдер не знает о заказе,
+	// и *accrual.TooManyRequestsError, если провайдер просит подождать.
+	CheckOrder(context.Context, string) (*accrual.GetOrderResponse, error)
+	// PausedFor возвращает, сколько ещё провайдер просит не присылать запросы.
+	PausedFor() time.Duration
+	Ping(context.Context) error
+}
 
-	return server
+func NewServer(r Repository, c *config.Settings, keys *authtoken.Keys, throttleStore throttle.Store,
+	provider AccrualProvider) *Server {
+	return &Server{
+		Repository: r,
+		Config:     c,
+		Accrual:    provider,
+		TokenKeys:  keys,
+		LoginThrottle: throttle.New(throttleStore, "login:", throttle.Config{
+			MaxFailures: c.LoginMaxFailures,
+			Window:      c.LoginFailureWindow,
+			BaseLockout: c.LoginLockout,
+			MaxLockout:  c.LoginMaxLockout,
+		}),
+		IPThrottle: throttle.New(throttleStore, "ip:", throttle.Config{
+			MaxFailures: c.LoginMaxFailuresPerIP,
+			Window:      c.LoginFailureWindow,
+			BaseLockout: c.LoginLockout,
+			MaxLockout:  c.LoginMaxLockout,
+		}),
+	}
 }
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/arseniy96/bonus-program/internal/apierror"
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/middlewares"
+	"github.com/arseniy96/bonus-program/internal/services/mycrypto"
+	"github.com/arseniy96/bonus-program/internal/services/validations"
+	"github.com/arseniy96/bonus-program/internal/store"
+)
+
+const (
+	sessionIDSize    = 16
+	refreshTokenSize = 32
+)
+
+// RefreshToken обменивает refresh-токен на новую пару токенов. Старый refresh-токен после этого
+// недействителен, а его повторное использование отзывает всю сессию.
+func (s *Server) RefreshToken(c *gin.Context) {
+	ctx, cancel := writeContext(c, 3*time.Second)
+	defer cancel()
+
+	var body RefreshTokenRequest
+	decoder := json.NewDecoder(c.Request.Body)
+	if err := decoder.Decode(&body); err != nil || body.RefreshToken == "" {
+		apierror.Abort(c, validations.Errors{{Field: "refresh_token", Message: "must not be empty"}})
+		return
+	}
+
+	refreshToken, err := mycrypto.CreateRandomToken(refreshTokenSize)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("build token error: %v", err)
+		apierror.Abort(c, err)
+		return
+	}
+
+	session, err := s.Repository.RotateRefreshToken(ctx,
+		mycrypto.HashToken(body.RefreshToken),
+		mycrypto.HashToken(refreshToken),
+		time.Now().Add(s.Config.RefreshTokenTTL))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRefreshTokenReused):
+			log := logger.FromContext(ctx)
+			if session != nil {
+				log = log.With("user_id", session.UserID, "session_id", session.ID)
+			}
+			log.Warn("refresh token reuse detected, session revoked")
+			apierror.Abort(c, apierror.Wrap(err, http.StatusUnauthorized, apierror.CodeInvalidRefreshToken, "refresh token was already used, session revoked"))
+		case errors.Is(err, store.ErrNowRows):
+			apierror.Abort(c, apierror.Wrap(err, http.StatusUnauthorized, apierror.CodeInvalidRefreshToken, "refresh token is invalid or expired"))
+		default:
+			logger.FromContext(ctx).Errorf("rotate refresh token error: %v", err)
+			apierror.Abort(c, err)
+		}
+		return
+	}
+
+	resp, err := authResponse(s, session, refreshToken)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("build token error: %v", err)
+		apierror.Abort(c, err)
+		return
+	}
+
+	c.Header("Authorization", resp.AccessToken)
+	c.JSON(http.StatusOK, resp)
+}
+
+// Logout отзывает текущую сессию: её refresh-токен больше не обменивается. Уже выпущенный токен доступа
+// AuthMiddleware проверяет без похода в базу, поэтому он действует до конца AccessTokenTTL.
+func (s *Server) Logout(c *gin.Context) {
+	principal, ok := middlewares.GetPrincipal(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+	ctx, cancel := writeContext(c, 1*time.Second)
+	defer cancel()
+
+	if err := s.Repository.RevokeSession(ctx, principal.UserID, principal.SessionID); err != nil && !errors.Is(err, store.ErrNowRows) {
+		logger.FromContext(ctx).Errorf("revoke session error: %v", err)
+		apierror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func (s *Server) GetSessions(c *gin.Context) {
+	principal, ok := middlewares.GetPrincipal(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 1*time.Second)
+	defer cancel()
+
+	sessions, err := s.Repository.FindActiveSessionsByUserID(ctx, principal.UserID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("find sessions error: %v", err)
+		apierror.Abort(c, err)
+		return
+	}
+
+	response := GetSessionsResponse{}
+	for _, session := range sessions {
+		response = append(response, SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt.Format(time.RFC3339),
+			LastUsedAt: session.LastUsedAt.Format(time.RFC3339),
+			Current:    session.ID == principal.SessionID,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteSession отзывает одну сессию пользователя по её ID.
+func (s *Server) DeleteSession(c *gin.Context) {
+	principal, ok := middlewares.GetPrincipal(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+	ctx, cancel := writeContext(c, 1*time.Second)
+	defer cancel()
+
+	if err := s.Repository.RevokeSession(ctx, principal.UserID, c.Param("id")); err != nil {
+		if errors.Is(err, store.ErrNowRows) {
+			apierror.Abort(c, apierror.Wrap(err, http.StatusNotFound, apierror.CodeNotFound, "session not found"))
+			return
+		}
+		logger.FromContext(ctx).Errorf("revoke session error: %v", err)
+		apierror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// DeleteSessions отзывает все сессии пользователя, кроме текущей.
+func (s *Server) DeleteSessions(c *gin.Context) {
+	principal, ok := middlewares.GetPrincipal(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+	ctx, cancel := writeContext(c, 1*time.Second)
+	defer cancel()
+
+	revoked, err := s.Repository.RevokeOtherSessions(ctx, principal.UserID, principal.SessionID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("revoke sessions error: %v", err)
+		apierror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
+
+// startSession заводит новую сессию для устройства, с которого пришёл запрос, и выпускает для неё токены.
+func startSession(ctx context.Context, s *Server, c *gin.Context, user *store.User) (*AuthResponse, error) {
+	sessionID, err := mycrypto.CreateRandomToken(sessionIDSize)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := mycrypto.CreateRandomToken(refreshTokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &store.Session{
+		ID:        sessionID,
+		UserID:    user.ID,
+		Login:     user.Login,
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+		ExpiresAt: time.Now().Add(s.Config.RefreshTokenTTL),
+	}
+	if err := s.Repository.CreateSession(ctx, session, mycrypto.HashToken(refreshToken)); err != nil {
+		return nil, err
+	}
+
+	return authResponse(s, session, refreshToken)
+}
+
+func authResponse(s *Server, session *store.Session, refreshToken string) (*AuthResponse, error) {
+	accessToken, err := s.TokenKeys.Issue(Claims{
+		UserID:    session.UserID,
+		Login:     session.Login,
+		SessionID: session.ID,
+	}, s.Config.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		Login:        "success",
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(s.Config.AccessTokenTTL.Seconds()),
+	}, nil
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/arseniy96/bonus-program/internal/config"
+	"github.com/arseniy96/bonus-program/internal/mocks"
+	"github.com/arseniy96/bonus-program/internal/store"
+)
+
+func TestServer_RefreshToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepository(ctrl)
+	m.EXPECT().RotateRefreshToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&store.Session{ID: testSessionID, UserID: 1, Login: "gopher"}, nil)
+	m.EXPECT().RotateRefreshToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&store.Session{ID: testSessionID, UserID: 1, Login: "gopher"}, store.ErrRefreshTokenReused)
+	m.EXPECT().RotateRefreshToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, store.ErrRefreshTokenReused)
+	m.EXPECT().RotateRefreshToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, store.ErrNowRows)
+
+	tests := []struct {
+		name       string
+		body       string
+		statusCode int
+	}{
+		{
+			name:       "success",
+			body:       `{"refresh_token":"token"}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "reused token",
+			body:       `{"refresh_token":"token"}`,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "reused token without session",
+			body:       `{"refresh_token":"token"}`,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "unknown token",
+			body:       `{"refresh_token":"token"}`,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "empty token",
+			body:       `{}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: m,
+				Config:     &config.Settings{AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour},
+				TokenKeys:  testKeys,
+			}
+
+			r := SetUpPublicRouter()
+			r.POST("/api/user/token/refresh", s.RefreshToken)
+			req, _ := http.NewRequest("POST", "/api/user/token/refresh", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			if tt.statusCode == http.StatusOK {
+				assert.NotEmpty(t, w.Header().Get("Authorization"))
+			}
+		})
+	}
+}
+
+func TestServer_GetSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := mocks.NewMockRepository(ctrl)
+	m.EXPECT().FindActiveSessionsByUserID(gomock.Any(), 1).Return([]store.Session{
+		{ID: testSessionID, UserAgent: "curl", IP: "127.0.0.1", CreatedAt: now, LastUsedAt: now},
+		{ID: "other", UserAgent: "firefox", IP: "10.0.0.1", CreatedAt: now, LastUsedAt: now},
+	}, nil)
+
+	s := &Server{Repository: m}
+	r := SetUpRouter()
+	r.GET("/api/user/sessions", s.GetSessions)
+	req, _ := http.NewRequest("GET", "/api/user/sessions", nil)
+	req.Header.Set("Authorization", allowedToken)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	responseData, _ := io.ReadAll(w.Body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[
+		{"id":"current_session","user_agent":"curl","ip":"127.0.0.1","created_at":"2024-01-01T00:00:00Z","last_used_at":"2024-01-01T00:00:00Z","current":true},
+		{"id":"other","user_agent":"firefox","ip":"10.0.0.1","created_at":"2024-01-01T00:00:00Z","last_used_at":"2024-01-01T00:00:00Z","current":false}
+	]`, string(responseData))
+}
+
+func TestServer_DeleteSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepository(ctrl)
+	m.EXPECT().RevokeSession(gomock.Any(), 1, "other").Return(nil)
+	m.EXPECT().RevokeSession(gomock.Any(), 1, "unknown").Return(store.ErrNowRows)
+
+	tests := []struct {
+		name       string
+		id         string
+		statusCode int
+	}{
+		{name: "success", id: "other", statusCode: http.StatusOK},
+		{name: "not found", id: "unknown", statusCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{Repository: m}
+			r := SetUpRouter()
+			r.DELETE("/api/user/sessions/:id", s.DeleteSession)
+			req, _ := http.NewRequest("DELETE", "/api/user/sessions/"+tt.id, nil)
+			req.Header.Set("Authorization", allowedToken)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+		})
+	}
+}
@@ -1,69 +1,67 @@
 package server
 
 import (
-	"context"
-	"fmt"
 	"io"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/arseniy96/bonus-program/internal/apierror"
 	"github.com/arseniy96/bonus-program/internal/logger"
-	"github.com/arseniy96/bonus-program/internal/services/mycrypto"
+	"github.com/arseniy96/bonus-program/internal/middlewares"
 	"github.com/arseniy96/bonus-program/internal/services/validations"
 	"github.com/arseniy96/bonus-program/internal/store"
 )
 
 func (s *Server) UploadOrderHandler(c *gin.Context) {
-	authHeader := c.GetHeader("Authorization")
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-	defer cancel()
-
-	token := mycrypto.HashFunc(authHeader)
-	user, err := s.Repository.FindUserByToken(ctx, token)
-	if err != nil {
-		logger.Log.Errorf("find user error: %v", err)
-		c.AbortWithError(http.StatusInternalServerError, err)
+	principal, ok := middlewares.GetPrincipal(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
 		return
 	}
+	ctx, cancel := writeContext(c, 1*time.Second)
+	defer cancel()
 
 	orderNumber, err := io.ReadAll(c.Request.Body)
 	if err != nil || len(orderNumber) == 0 {
-		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("request error"))
+		apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeBadRequest, "order number is required"))
 		return
 	}
 	if err := validations.LuhnValidate(string(orderNumber)); err != nil {
-		c.AbortWithError(http.StatusUnprocessableEntity, fmt.Errorf("invalid order_number"))
+		apierror.Abort(c, err)
 		return
 	}
 
 	order, err := s.Repository.FindOrderByOrderNumber(ctx, string(orderNumber))
 	if err != nil {
 		if err == store.ErrNowRows {
-			order, err = s.Repository.CreateOrder(ctx, user.ID, string(orderNumber), store.OrderStatusNew)
+			order, err = s.Repository.CreateOrder(ctx, principal.UserID, string(orderNumber), store.OrderStatusNew)
 			if err != nil {
-				logger.Log.Errorf("create order error: %v", err)
-				c.AbortWithError(http.StatusInternalServerError, fmt.Errorf("save order error %v", err))
+				logger.FromContext(ctx).Errorf("create order error: %v", err)
+				apierror.Abort(c, err)
 				return
 			}
-			s.OrdersQueue <- order // кладём в очередь для фоновой обработки
+			// задача на опрос системы начислений создаётся вместе с заказом
+			logger.FromContext(ctx).Infow("order saved",
+				"user_id", order.UserID,
+				"order_number", order.OrderNumber)
 
 			c.String(http.StatusAccepted, "order saved")
 			return
 		}
-		c.AbortWithError(http.StatusInternalServerError, err)
+		apierror.Abort(c, err)
 		return
 	}
 
-	logger.Log.Infow("order already exists",
+	logger.FromContext(ctx).Infow("order already exists",
 		"user_id", order.UserID,
 		"order_number", order.OrderNumber)
 
-	if order.UserID == user.ID {
+	if order.UserID == principal.UserID {
 		c.String(http.StatusOK, "order already exists")
 		return
 	}
 
-	c.AbortWithError(http.StatusConflict, fmt.Errorf("order already exists"))
+	apierror.Abort(c, apierror.New(http.StatusConflict, apierror.CodeOrderOwnedByAnotherUser, "order was uploaded by another user"))
 }
@@ -1,52 +1,54 @@
 package server
 
 import (
-	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/arseniy96/bonus-program/internal/apierror"
 	"github.com/arseniy96/bonus-program/internal/logger"
-	"github.com/arseniy96/bonus-program/internal/services/converter"
-	"github.com/arseniy96/bonus-program/internal/services/mycrypto"
+	"github.com/arseniy96/bonus-program/internal/metrics"
+	"github.com/arseniy96/bonus-program/internal/middlewares"
+	"github.com/arseniy96/bonus-program/internal/services/validations"
+	"github.com/arseniy96/bonus-program/internal/store"
 )
 
 func (s *Server) WithdrawHandler(c *gin.Context) {
-	authHeader := c.GetHeader("Authorization")
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-	defer cancel()
-
-	token := mycrypto.HashFunc(authHeader)
-	user, err := s.Repository.FindUserByToken(ctx, token)
-	if err != nil {
-		logger.Log.Errorf("find user error: %v", err)
-		c.AbortWithError(http.StatusInternalServerError, err)
+	principal, ok := middlewares.GetPrincipal(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
 		return
 	}
+	ctx, cancel := writeContext(c, 1*time.Second)
+	defer cancel()
 
 	var body WithdrawRequest
 	decoder := json.NewDecoder(c.Request.Body)
 	if err := decoder.Decode(&body); err != nil {
-		c.AbortWithError(http.StatusBadRequest, err)
+		apierror.Abort(c, apierror.Wrap(err, http.StatusBadRequest, apierror.CodeBadRequest, "malformed JSON body"))
 		return
 	}
-	if body.Sum > converter.ConvertFromCent(user.Bonuses) {
-		c.AbortWithError(http.StatusPaymentRequired, fmt.Errorf("insufficient funds"))
-		return
-	}
-	if body.Sum == 0 {
-		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid amount"))
+	if body.Sum <= 0 {
+		apierror.Abort(c, validations.Errors{{Field: "sum", Message: "must be positive"}})
 		return
 	}
 
-	err = s.Repository.SaveWithdrawBonuses(ctx, user.ID, body.Order, converter.ConvertToCent(body.Sum))
+	// достаточность баллов проверяется в той же транзакции, что и списание
+	err := s.Repository.SaveWithdrawBonuses(ctx, principal.UserID, body.Order, body.Sum)
 	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+		if errors.Is(err, store.ErrInsufficientFunds) {
+			apierror.Abort(c, err)
+			return
+		}
+		logger.FromContext(ctx).Errorf("save withdraw error: %v", err)
+		apierror.Abort(c, err)
 		return
 	}
 
+	metrics.BonusesWithdrawn.Add(metrics.Points(body.Sum))
+
 	c.JSON(http.StatusOK, gin.H{})
 }
@@ -1,7 +1,7 @@
 package server
 
 import (
-	"fmt"
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/arseniy96/bonus-program/internal/mocks"
+	"github.com/arseniy96/bonus-program/internal/services/money"
 	"github.com/arseniy96/bonus-program/internal/store"
 )
 
@@ -20,10 +21,8 @@ func TestServer_WithdrawHandler(t *testing.T) {
 	defer ctrl.Finish()
 
 	m := mocks.NewMockRepository(ctrl)
-	m.EXPECT().FindUserByToken(gomock.Any(), allowedTokenHash).Return(&store.User{ID: 1, Bonuses: 100000}, nil)
-	m.EXPECT().FindUserByToken(gomock.Any(), allowedToken2Hash).Return(&store.User{ID: 2, Bonuses: 10000}, nil)
-	m.EXPECT().FindUserByToken(gomock.Any(), wrongTokenHash).Return(nil, fmt.Errorf("invalid token"))
-	m.EXPECT().SaveWithdrawBonuses(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	m.EXPECT().SaveWithdrawBonuses(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil)
+	m.EXPECT().SaveWithdrawBonuses(gomock.Any(), 2, gomock.Any(), gomock.Any()).Return(store.ErrInsufficientFunds)
 
 	type fields struct {
 		Repository Repository
@@ -59,8 +58,8 @@ func TestServer_WithdrawHandler(t *testing.T) {
 				body:       `{"order":"123","sum":500}`,
 			},
 			want: results{
-				statusCode: http.StatusInternalServerError,
-				response:   ``,
+				statusCode: http.StatusUnauthorized,
+				response:   `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"missing or invalid access token","instance":"/api/user/balance/withdraw","code":"unauthorized"}`,
 			},
 		},
 		{
@@ -72,7 +71,7 @@ func TestServer_WithdrawHandler(t *testing.T) {
 			},
 			want: results{
 				statusCode: http.StatusPaymentRequired,
-				response:   ``,
+				response:   `{"type":"about:blank","title":"Payment Required","status":402,"detail":"not enough bonuses on balance","instance":"/api/user/balance/withdraw","code":"insufficient_funds"}`,
 			},
 		},
 	}
@@ -96,3 +95,31 @@ func TestServer_WithdrawHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_WithdrawHandler_ClientGone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepository(ctrl)
+	m.EXPECT().SaveWithdrawBonuses(gomock.Any(), 1, "123", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ int, _ string, _ money.Amount) error {
+			// списание не прерывается, если клиент уже отключился
+			assert.NoError(t, ctx.Err())
+			_, ok := ctx.Deadline()
+			assert.True(t, ok)
+			return nil
+		})
+
+	s := &Server{Repository: m}
+	r := SetUpRouter()
+	r.POST("/api/user/balance/withdraw", s.WithdrawHandler)
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(reqCtx, "POST", "/api/user/balance/withdraw", strings.NewReader(`{"order":"123","sum":500}`))
+	req.Header.Set("Authorization", allowedToken)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
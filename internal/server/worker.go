@@ -2,70 +2,371 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/metrics"
 	"github.com/arseniy96/bonus-program/internal/services/accrual"
-	"github.com/arseniy96/bonus-program/internal/services/converter"
+	"github.com/arseniy96/bonus-program/internal/services/breaker"
+	"github.com/arseniy96/bonus-program/internal/services/ctxutil"
+	"github.com/arseniy96/bonus-program/internal/services/money"
 	"github.com/arseniy96/bonus-program/internal/store"
+	"github.com/arseniy96/bonus-program/internal/tracing"
 )
 
-const Delay = 3 * time.Second
+const (
+	// Delay — пауза между проходами очереди и первая задержка повторного опроса заказа
+	Delay = 3 * time.Second
+	// MaxRetryDelay — больше этого задержка между опросами одного заказа не растёт
+	MaxRetryDelay = 10 * time.Minute
+	// JobLease — на сколько задача скрывается от других обработчиков после того, как её взяли в работу
+	JobLease = time.Minute
+	// CheckTimeout должен быть меньше JobLease, иначе задачу может забрать другой обработчик
+	CheckTimeout = 30 * time.Second
+)
 
-func (s *Server) OrdersWorker() {
-	var messages []*store.Order
-	ticker := time.NewTicker(Delay)
+// OrdersWorker запускает пул из Config.WorkersCount обработчиков и раздаёт им задачи из очереди.
+// Пока задачи есть, следующая пачка забирается сразу, иначе — через Delay.
+// После отмены ctx новые задачи не забираются, а текущим даётся Config.ShutdownTimeout на завершение.
+// Затем их опрос системы начислений отменяется, и задачи возвращаются в очередь без траты попытки.
+// Метод возвращается, когда все обработчики остановились.
+func (s *Server) OrdersWorker(ctx context.Context) {
+	workersCount := s.Config.WorkersCount
+	if workersCount < 1 {
+		workersCount = 1
+	}
 
-	for {
+	jobCtx, cancelJobs := drainContext(ctx, s.Config.ShutdownTimeout)
+	defer cancelJobs()
+	jobs := make(chan store.OrderJob)
+	var wg sync.WaitGroup
+	for i := 0; i < workersCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				processJob(jobCtx, s, job)
+			}
+		}()
+	}
+
+	dispatchJobs(ctx, s, jobs, workersCount)
+	close(jobs)
+	wg.Wait()
+	logger.Log.Info("orders worker stopped")
+}
+
+// drainContext возвращает контекст, который отменяется через drain после отмены ctx.
+func drainContext(ctx context.Context, drain time.Duration) (context.Context, context.CancelFunc) {
+	drainCtx, cancel := context.WithCancel(ctxutil.WithoutCancel(ctx))
+	go func() {
 		select {
-		case msg := <-s.OrdersQueue:
-			messages = append(messages, msg)
-		case <-ticker.C:
-			if len(messages) == 0 {
-				continue
+		case <-ctx.Done():
+		case <-drainCtx.Done():
+			return
+		}
+		timer := time.NewTimer(drain)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel()
+		case <-drainCtx.Done():
+		}
+	}()
+	return drainCtx, cancel
+}
+
+func dispatchJobs(ctx context.Context, s *Server, jobs chan<- store.OrderJob, limit int) {
+	for {
+		s.beat()
+
+		// после ответа 429 и пока разомкнут circuit breaker не забираем новые задачи;
+		// ждём не дольше Delay за раз, чтобы не пропускать heartbeat
+		if pause := s.Accrual.PausedFor(); pause > 0 {
+			if pause > Delay {
+				pause = Delay
+			}
+			if !sleep(ctx, pause) {
+				return
 			}
+			continue
+		}
 
-			for _, order := range messages {
-				res, err := accrual.CheckOrder(s.Config.AccrualHost, order.OrderNumber)
-				logger.Log.Info(fmt.Sprintf("%v", res))
-				if err != nil {
-					logger.Log.Errorf("accrual check order error: %v", err)
-					s.OrdersQueue <- order
-					continue
+		claimed, err := claimJobs(s, limit)
+		if err != nil {
+			logger.Log.Errorf("claim order jobs error: %v", err)
+		}
+		for i, job := range claimed {
+			select {
+			case jobs <- job: // ждём, пока освободится обработчик
+			case <-ctx.Done():
+				// не дожидаемся JobLease, а сразу отдаём оставшиеся задачи другим репликам
+				for _, rest := range claimed[i:] {
+					releaseJob(logger.WithRequestID(ctx, rest.RequestID), s, rest, 0, "")
 				}
+				return
+			}
+		}
 
-				if !hasFinalStatus(res.Status) {
-					// система ещё не обработала заказ – отправляем обратно в очередь
-					logger.Log.Debugw("accrual has not processed the order yet",
-						"order_number", order.OrderNumber,
-						"current_accrual_status", res.Status)
-					s.OrdersQueue <- order
-					continue
-				}
+		if len(claimed) < limit && !sleep(ctx, Delay) {
+			return
+		}
+	}
+}
 
-				err = updateOrder(s, order, res.Status, res.Accrual)
-				if err != nil {
-					logger.Log.Error(err)
-					s.OrdersQueue <- order
-				}
+func (s *Server) beat() {
+	s.workerHeartbeat.Store(time.Now().UnixNano())
+}
+
+// lastHeartbeat возвращает время последнего heartbeat обработчика заказов или нулевое время,
+// если он ещё не запускался.
+func (s *Server) lastHeartbeat() time.Time {
+	beat := s.workerHeartbeat.Load()
+	if beat == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, beat)
+}
+
+// sleep ждёт d и возвращает false, если ctx отменили раньше.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func processJob(ctx context.Context, s *Server, job store.OrderJob) {
+	// логи задачи связываются с запросом, загрузившим заказ
+	ctx = logger.WithRequestID(ctx, job.RequestID)
+	log := logger.FromContext(ctx).With("job_id", job.ID)
+
+	order := job.Order
+	ctx, span := startJobSpan(ctx, job)
+	defer span.End()
+
+	res, err := checkOrder(ctx, s, order.OrderNumber)
+	if err != nil {
+		var tooManyErr *accrual.TooManyRequestsError
+		switch {
+		case errors.As(err, &tooManyErr):
+			// заказ тут ни при чём – просто повторим, когда система начислений разрешит, не засчитывая попытку
+			releaseJob(ctx, s, job, tooManyErr.RetryAfter, err.Error())
+		case errors.Is(err, breaker.ErrOpen):
+			// задачу взяли до того, как breaker разомкнулся; вернём её, когда он снова пропустит запросы
+			log.Debugw("accrual circuit breaker is open", "order_number", order.OrderNumber)
+			delay := s.Accrual.PausedFor()
+			if delay < Delay {
+				delay = Delay
 			}
-			messages = nil
+			releaseJob(ctx, s, job, delay, err.Error())
+		case errors.Is(err, context.Canceled):
+			// запрос отменили с нашей стороны, о заказе это ничего не говорит
+			releaseJob(ctx, s, job, Delay, err.Error())
+		case errors.Is(err, accrual.ErrOrderNotRegistered):
+			log.Debugw("order is not registered in accrual system yet",
+				"order_number", order.OrderNumber)
+			rescheduleJob(ctx, s, job, err.Error())
 		default:
-			continue
+			log.Errorf("accrual check order error: %v", err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			rescheduleJob(ctx, s, job, err.Error())
 		}
+		return
+	}
+
+	if !hasFinalStatus(res.Status) {
+		// система ещё не обработала заказ – это не сбой, поэтому попытку не засчитываем: иначе заказ,
+		// который долго считается, исчерпал бы OrderMaxAttempts задолго до OrderMaxAge
+		log.Debugw("accrual has not processed the order yet",
+			"order_number", order.OrderNumber,
+			"current_accrual_status", res.Status)
+		span.SetAttributes(attribute.String("accrual.status", res.Status))
+		lastError := fmt.Sprintf("order is %s in accrual system", res.Status)
+		if tooOld(s, job) {
+			deadLetterJob(ctx, s, job, lastError)
+			return
+		}
+		releaseJob(ctx, s, job, pollDelay(time.Since(job.EnqueuedAt)), lastError)
+		return
+	}
+
+	log.Debugw("accrual response",
+		"order_number", order.OrderNumber,
+		"status", res.Status,
+		"accrual", res.Accrual)
+	span.SetAttributes(attribute.String("accrual.status", res.Status))
+	updated, err := updateOrder(ctx, s, job, res.Status, res.Accrual)
+	if err != nil {
+		log.Error(err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		// ошибка нашей базы, а не заказа: не тратим попытку, иначе сбой базы отправил бы заказы в dead letter
+		releaseJob(ctx, s, job, Delay, err.Error())
+		return
+	}
+	if !updated {
+		// заказ уже финализировал другой обработчик, он же учёл его в метриках
+		log.Debugw("order is already finalized", "order_number", order.OrderNumber)
+		return
 	}
+	metrics.OrdersProcessed.WithLabelValues(res.Status).Inc()
+	if res.Status == accrual.OrderStatusProcessed {
+		metrics.BonusesAccrued.Add(metrics.Points(res.Accrual))
+	}
+}
+
+// startJobSpan начинает трассу обработки задачи. Запрос, загрузивший заказ, к этому моменту давно завершён,
+// поэтому спан не продолжает его трассу, а ссылается на неё.
+func startJobSpan(ctx context.Context, job store.OrderJob) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{
+		trace.WithNewRoot(),
+		trace.WithAttributes(
+			attribute.Int("job.id", job.ID),
+			attribute.Int("job.attempt", job.Attempts),
+			attribute.String("order.number", job.Order.OrderNumber),
+			// сколько заказ уже ждёт финального статуса
+			attribute.Float64("order.age_seconds", time.Since(job.Order.CreatedAt).Seconds()),
+		),
+	}
+	if link, ok := tracing.LinkTo(job.TraceParent); ok {
+		opts = append(opts, trace.WithLinks(link))
+	}
+	return tracing.Start(ctx, "worker.ProcessOrder", opts...)
 }
 
 func hasFinalStatus(status string) bool {
 	return status == accrual.OrderStatusInvalid || status == accrual.OrderStatusProcessed
 }
 
-func updateOrder(s *Server, order *store.Order, accrualStatus string, accrualBonus float64) error {
+func claimJobs(s *Server, limit int) ([]store.OrderJob, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	bonusAmount := converter.ConvertToCent(accrualBonus)
+	return s.Repository.ClaimOrderJobs(ctx, limit, JobLease)
+}
+
+func checkOrder(ctx context.Context, s *Server, orderNumber string) (*accrual.GetOrderResponse, error) {
+	// запрос может ждать в ограничителе частоты, но не дольше, чем задача закреплена за нами
+	ctx, cancel := context.WithTimeout(ctx, CheckTimeout)
+	defer cancel()
+
+	return s.Accrual.CheckOrder(ctx, orderNumber)
+}
+
+// rescheduleJob откладывает следующий опрос заказа с экспоненциально растущей задержкой, а если попытки
+// или время исчерпаны, переводит задачу в dead letter.
+func rescheduleJob(ctx context.Context, s *Server, job store.OrderJob, lastError string) {
+	if retriesExhausted(s, job) {
+		deadLetterJob(ctx, s, job, lastError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctxutil.WithoutCancel(ctx), 3*time.Second)
+	defer cancel()
+	if err := s.Repository.RescheduleOrderJob(ctx, job.ID, retryDelay(job.Attempts), lastError); err != nil {
+		// задача всё равно вернётся в работу, когда истечёт JobLease
+		logger.FromContext(ctx).Errorw("reschedule order job error",
+			"order_number", job.Order.OrderNumber,
+			"error", err)
+	}
+}
+
+func deadLetterJob(ctx context.Context, s *Server, job store.OrderJob, lastError string) {
+	ctx, cancel := context.WithTimeout(ctxutil.WithoutCancel(ctx), 3*time.Second)
+	defer cancel()
+	log := logger.FromContext(ctx)
+
+	if err := s.Repository.DeadLetterOrderJob(ctx, job.ID, lastError); err != nil {
+		// задача всё равно вернётся в работу, когда истечёт JobLease, и попадёт в dead letter в следующий раз
+		log.Errorw("dead letter order job error", "order_number", job.Order.OrderNumber, "error", err)
+		return
+	}
+	metrics.OrdersDeadLettered.Inc()
+	log.Warnw("order moved to dead letter",
+		"order_number", job.Order.OrderNumber,
+		"attempts", job.Attempts,
+		"last_error", lastError)
+}
+
+// releaseJob возвращает задачу в очередь через delay, не засчитывая попытку.
+func releaseJob(ctx context.Context, s *Server, job store.OrderJob, delay time.Duration, lastError string) {
+	ctx, cancel := context.WithTimeout(ctxutil.WithoutCancel(ctx), 3*time.Second)
+	defer cancel()
+
+	if err := s.Repository.ReleaseOrderJob(ctx, job.ID, delay, lastError); err != nil {
+		// задача всё равно вернётся в работу, когда истечёт JobLease
+		logger.FromContext(ctx).Errorw("release order job error",
+			"order_number", job.Order.OrderNumber,
+			"error", err)
+	}
+}
+
+func retriesExhausted(s *Server, job store.OrderJob) bool {
+	if s.Config.OrderMaxAttempts > 0 && job.Attempts >= s.Config.OrderMaxAttempts {
+		return true
+	}
+	return tooOld(s, job)
+}
+
+func tooOld(s *Server, job store.OrderJob) bool {
+	return s.Config.OrderMaxAge > 0 && time.Since(job.EnqueuedAt) >= s.Config.OrderMaxAge
+}
+
+// retryDelay возвращает задержку перед следующим опросом после attempt-й неудачной попытки: Delay,
+// удваивающийся с каждой попыткой, но не больше MaxRetryDelay. Случайная половина задержки разносит
+// во времени повторы заказов, упавших одновременно.
+func retryDelay(attempt int) time.Duration {
+	delay := MaxRetryDelay
+	if attempt < 1 {
+		attempt = 1
+	}
+	// после 20 удвоений Delay заведомо больше MaxRetryDelay, а сдвиг дальше может переполнить int64
+	if attempt <= 20 {
+		if d := Delay << (attempt - 1); d < MaxRetryDelay {
+			delay = d
+		}
+	}
+	return jitter(delay)
+}
+
+// pollDelay возвращает задержку перед следующим опросом заказа, который система начислений ещё обрабатывает:
+// десятую часть того, сколько заказ уже ждёт, но от Delay до MaxRetryDelay. Так только что загруженный
+// заказ опрашивается часто, а долго обрабатываемый — не чаще раза в MaxRetryDelay.
+func pollDelay(waiting time.Duration) time.Duration {
+	delay := waiting / 10
+	if delay < Delay {
+		delay = Delay
+	}
+	if delay > MaxRetryDelay {
+		delay = MaxRetryDelay
+	}
+	return jitter(delay)
+}
+
+// jitter возвращает случайную задержку от половины delay до delay.
+func jitter(delay time.Duration) time.Duration {
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+func updateOrder(ctx context.Context, s *Server, job store.OrderJob, accrualStatus string, accrualBonus money.Amount) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctxutil.WithoutCancel(ctx), 3*time.Second)
+	defer cancel()
 
-	return s.Repository.UpdateOrderStatus(ctx, order, accrualStatus, bonusAmount)
+	return s.Repository.UpdateOrderStatus(ctx, &job.Order, accrualStatus, accrualBonus)
 }
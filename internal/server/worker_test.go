@@ -0,0 +1,417 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/arseniy96/bonus-program/internal/accrualstub"
+	"github.com/arseniy96/bonus-program/internal/config"
+	"github.com/arseniy96/bonus-program/internal/metrics"
+	"github.com/arseniy96/bonus-program/internal/mocks"
+	"github.com/arseniy96/bonus-program/internal/services/accrual"
+	"github.com/arseniy96/bonus-program/internal/services/breaker"
+	"github.com/arseniy96/bonus-program/internal/services/money"
+	"github.com/arseniy96/bonus-program/internal/store"
+)
+
+var testJob = store.OrderJob{
+	ID:         7,
+	Attempts:   1,
+	CreatedAt:  time.Now(),
+	EnqueuedAt: time.Now(),
+	Order:      store.Order{ID: 3, OrderNumber: "12345678903", Status: store.OrderStatusNew, UserID: 1, CreatedAt: time.Now()},
+}
+
+// delayMatcher проверяет, что задержка попадает в диапазон retryDelay для попытки attempt.
+type delayMatcher struct {
+	attempt int
+}
+
+func (m delayMatcher) Matches(x interface{}) bool {
+	d, ok := x.(time.Duration)
+	if !ok {
+		return false
+	}
+	full := Delay
+	for i := 1; i < m.attempt && full < MaxRetryDelay; i++ {
+		full *= 2
+	}
+	if full > MaxRetryDelay {
+		full = MaxRetryDelay
+	}
+	return d >= full/2 && d <= full
+}
+
+func (m delayMatcher) String() string {
+	return fmt.Sprintf("retry delay for attempt %d", m.attempt)
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt := 0; attempt < 100; attempt++ {
+		want := attempt
+		if want < 1 {
+			want = 1
+		}
+		d := retryDelay(attempt)
+		assert.True(t, delayMatcher{attempt: want}.Matches(d), "attempt %d: %v", attempt, d)
+	}
+	assert.LessOrEqual(t, retryDelay(1000), MaxRetryDelay)
+	assert.GreaterOrEqual(t, retryDelay(1000), MaxRetryDelay/2)
+}
+
+func TestPollDelay(t *testing.T) {
+	tests := []struct {
+		waiting time.Duration
+		max     time.Duration
+	}{
+		{waiting: 0, max: Delay},
+		{waiting: 10 * time.Second, max: Delay},
+		{waiting: 10 * time.Minute, max: time.Minute},
+		{waiting: 72 * time.Hour, max: MaxRetryDelay},
+	}
+	for _, tt := range tests {
+		d := pollDelay(tt.waiting)
+		assert.True(t, d >= tt.max/2 && d <= tt.max, "waiting %v: %v", tt.waiting, d)
+	}
+}
+
+func TestProcessJob(t *testing.T) {
+	errAccrualDown := errors.New("connection refused")
+	errDBDown := errors.New("db is down")
+
+	exhaustedJob := testJob
+	exhaustedJob.Attempts = 5
+	oldJob := testJob
+	oldJob.EnqueuedAt = time.Now().Add(-2 * time.Hour)
+	// заказ создан давно, но его вернули из dead letter — возраст считается заново
+	requeuedJob := testJob
+	requeuedJob.CreatedAt = time.Now().Add(-2 * time.Hour)
+
+	tests := []struct {
+		name      string
+		job       *store.OrderJob
+		result    *accrual.GetOrderResponse
+		err       error
+		pausedFor time.Duration
+		expect    func(m *mocks.MockRepository)
+	}{
+		{
+			name:   "processed order is finalized",
+			result: &accrual.GetOrderResponse{Order: "12345678903", Status: accrual.OrderStatusProcessed, Accrual: 72998},
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().UpdateOrderStatus(gomock.Any(), &testJob.Order, accrual.OrderStatusProcessed, money.Amount(72998)).Return(true, nil)
+			},
+		},
+		{
+			name:   "invalid order is finalized",
+			result: &accrual.GetOrderResponse{Order: "12345678903", Status: accrual.OrderStatusInvalid},
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().UpdateOrderStatus(gomock.Any(), &testJob.Order, accrual.OrderStatusInvalid, money.Amount(0)).Return(true, nil)
+			},
+		},
+		{
+			name:   "order in progress is checked again",
+			result: &accrual.GetOrderResponse{Order: "12345678903", Status: accrual.OrderStatusProcessing},
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().ReleaseOrderJob(gomock.Any(), testJob.ID, delayMatcher{attempt: 1}, "order is PROCESSING in accrual system").Return(nil)
+			},
+		},
+		{
+			name:   "order in progress does not spend attempts",
+			job:    &exhaustedJob,
+			result: &accrual.GetOrderResponse{Order: "12345678903", Status: accrual.OrderStatusRegistered},
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().ReleaseOrderJob(gomock.Any(), testJob.ID, delayMatcher{attempt: 1}, "order is REGISTERED in accrual system").Return(nil)
+			},
+		},
+		{
+			name: "unregistered order is rescheduled",
+			err:  accrual.ErrOrderNotRegistered,
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().RescheduleOrderJob(gomock.Any(), testJob.ID, delayMatcher{attempt: 1}, accrual.ErrOrderNotRegistered.Error()).Return(nil)
+			},
+		},
+		{
+			name: "rate limited order waits for Retry-After",
+			err:  &accrual.TooManyRequestsError{RetryAfter: time.Minute, Limit: 120},
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().ReleaseOrderJob(gomock.Any(), testJob.ID, time.Minute, gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:      "open circuit postpones the order until it closes",
+			err:       breaker.ErrOpen,
+			pausedFor: time.Minute,
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().ReleaseOrderJob(gomock.Any(), testJob.ID, time.Minute, breaker.ErrOpen.Error()).Return(nil)
+			},
+		},
+		{
+			name: "half-open circuit postpones the order for Delay",
+			err:  breaker.ErrOpen,
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().ReleaseOrderJob(gomock.Any(), testJob.ID, Delay, breaker.ErrOpen.Error()).Return(nil)
+			},
+		},
+		{
+			name: "accrual error is recorded",
+			err:  errAccrualDown,
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().RescheduleOrderJob(gomock.Any(), testJob.ID, delayMatcher{attempt: 1}, errAccrualDown.Error()).Return(nil)
+			},
+		},
+		{
+			name: "retries back off exponentially",
+			job:  &store.OrderJob{ID: testJob.ID, Attempts: 4, CreatedAt: time.Now(), EnqueuedAt: time.Now(), Order: testJob.Order},
+			err:  errAccrualDown,
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().RescheduleOrderJob(gomock.Any(), testJob.ID, delayMatcher{attempt: 4}, errAccrualDown.Error()).Return(nil)
+			},
+		},
+		{
+			name: "order out of attempts goes to dead letter",
+			job:  &exhaustedJob,
+			err:  accrual.ErrOrderNotRegistered,
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().DeadLetterOrderJob(gomock.Any(), testJob.ID, accrual.ErrOrderNotRegistered.Error()).Return(nil)
+			},
+		},
+		{
+			name:   "too old order goes to dead letter",
+			job:    &oldJob,
+			result: &accrual.GetOrderResponse{Order: "12345678903", Status: accrual.OrderStatusRegistered},
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().DeadLetterOrderJob(gomock.Any(), testJob.ID, "order is REGISTERED in accrual system").Return(nil)
+			},
+		},
+		{
+			name:   "requeued order is checked again",
+			job:    &requeuedJob,
+			result: &accrual.GetOrderResponse{Order: "12345678903", Status: accrual.OrderStatusRegistered},
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().ReleaseOrderJob(gomock.Any(), testJob.ID, delayMatcher{attempt: 1}, "order is REGISTERED in accrual system").Return(nil)
+			},
+		},
+		{
+			name: "canceled check does not spend attempts",
+			job:  &exhaustedJob,
+			err:  context.Canceled,
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().ReleaseOrderJob(gomock.Any(), testJob.ID, Delay, context.Canceled.Error()).Return(nil)
+			},
+		},
+		{
+			name: "rate limited order does not spend attempts",
+			job:  &exhaustedJob,
+			err:  &accrual.TooManyRequestsError{RetryAfter: time.Minute, Limit: 120},
+			expect: func(m *mocks.MockRepository) {
+				m.EXPECT().ReleaseOrderJob(gomock.Any(), testJob.ID, time.Minute, gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:   "failed update is retried without spending attempts",
+			job:    &exhaustedJob,
+			result: &accrual.GetOrderResponse{Order: "12345678903", Status: accrual.OrderStatusProcessed, Accrual: 500},
+			expect: func(m *mocks.MockRepository) {
+				gomock.InOrder(
+					m.EXPECT().UpdateOrderStatus(gomock.Any(), &exhaustedJob.Order, accrual.OrderStatusProcessed, money.Amount(500)).Return(false, errDBDown),
+					m.EXPECT().ReleaseOrderJob(gomock.Any(), testJob.ID, Delay, errDBDown.Error()).Return(nil),
+				)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			job := testJob
+			if tt.job != nil {
+				job = *tt.job
+			}
+			m := mocks.NewMockRepository(ctrl)
+			tt.expect(m)
+			provider := mocks.NewMockAccrualProvider(ctrl)
+			provider.EXPECT().CheckOrder(gomock.Any(), testJob.Order.OrderNumber).Return(tt.result, tt.err)
+			provider.EXPECT().PausedFor().Return(tt.pausedFor).AnyTimes()
+
+			s := &Server{
+				Repository: m,
+				Config:     &config.Settings{OrderMaxAttempts: 5, OrderMaxAge: time.Hour},
+				Accrual:    provider,
+			}
+			processJob(context.Background(), s, job)
+		})
+	}
+}
+
+func TestServer_OrdersWorker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := mocks.NewMockRepository(ctrl)
+	gomock.InOrder(
+		m.EXPECT().ClaimOrderJobs(gomock.Any(), 1, JobLease).Return([]store.OrderJob{testJob}, nil),
+		m.EXPECT().ClaimOrderJobs(gomock.Any(), 1, JobLease).Return(nil, nil).AnyTimes(),
+	)
+	m.EXPECT().UpdateOrderStatus(gomock.Any(), &testJob.Order, accrual.OrderStatusProcessed, money.Amount(10000)).
+		DoAndReturn(func(context.Context, *store.Order, string, money.Amount) (bool, error) {
+			// заказ обработан — останавливаем обработчик
+			cancel()
+			return true, nil
+		})
+
+	s := &Server{
+		Repository: m,
+		Config:     &config.Settings{WorkersCount: 1},
+		Accrual: accrualstub.NewFixtureProvider(map[string]accrual.GetOrderResponse{
+			testJob.Order.OrderNumber: {Status: accrual.OrderStatusProcessed, Accrual: 10000},
+		}),
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.OrdersWorker(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("orders worker did not stop")
+	}
+	assert.False(t, s.lastHeartbeat().IsZero())
+}
+
+func TestServer_OrdersWorker_FinishesInFlightJobs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := mocks.NewMockRepository(ctrl)
+	gomock.InOrder(
+		m.EXPECT().ClaimOrderJobs(gomock.Any(), 1, JobLease).Return([]store.OrderJob{testJob}, nil),
+		m.EXPECT().ClaimOrderJobs(gomock.Any(), 1, JobLease).Return(nil, nil).AnyTimes(),
+	)
+	m.EXPECT().UpdateOrderStatus(gomock.Any(), &testJob.Order, accrual.OrderStatusProcessed, money.Amount(10000)).Return(true, nil)
+
+	provider := mocks.NewMockAccrualProvider(ctrl)
+	provider.EXPECT().PausedFor().Return(time.Duration(0)).AnyTimes()
+	provider.EXPECT().CheckOrder(gomock.Any(), testJob.Order.OrderNumber).
+		DoAndReturn(func(checkCtx context.Context, _ string) (*accrual.GetOrderResponse, error) {
+			// сервис останавливается, пока заказ опрашивается
+			cancel()
+			assert.NoError(t, checkCtx.Err())
+			_, ok := checkCtx.Deadline()
+			assert.True(t, ok)
+			return &accrual.GetOrderResponse{Order: testJob.Order.OrderNumber, Status: accrual.OrderStatusProcessed, Accrual: 10000}, nil
+		})
+
+	s := &Server{
+		Repository: m,
+		Config:     &config.Settings{WorkersCount: 1, ShutdownTimeout: time.Minute},
+		Accrual:    provider,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.OrdersWorker(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("orders worker did not stop")
+	}
+}
+
+func TestServer_OrdersWorker_CancelsJobsAfterShutdownTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := mocks.NewMockRepository(ctrl)
+	gomock.InOrder(
+		m.EXPECT().ClaimOrderJobs(gomock.Any(), 1, JobLease).Return([]store.OrderJob{testJob}, nil),
+		m.EXPECT().ClaimOrderJobs(gomock.Any(), 1, JobLease).Return(nil, nil).AnyTimes(),
+	)
+	// отменённый опрос возвращает задачу в очередь, не тратя попытку
+	m.EXPECT().ReleaseOrderJob(gomock.Any(), testJob.ID, Delay, context.Canceled.Error()).Return(nil)
+
+	provider := mocks.NewMockAccrualProvider(ctrl)
+	provider.EXPECT().PausedFor().Return(time.Duration(0)).AnyTimes()
+	provider.EXPECT().CheckOrder(gomock.Any(), testJob.Order.OrderNumber).
+		DoAndReturn(func(checkCtx context.Context, _ string) (*accrual.GetOrderResponse, error) {
+			// система начислений зависла, а сервис останавливается
+			cancel()
+			<-checkCtx.Done()
+			return nil, checkCtx.Err()
+		})
+
+	s := &Server{
+		Repository: m,
+		Config:     &config.Settings{WorkersCount: 1, ShutdownTimeout: 50 * time.Millisecond},
+		Accrual:    provider,
+	}
+
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		s.OrdersWorker(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("orders worker did not stop")
+	}
+	assert.Less(t, time.Since(start), CheckTimeout)
+}
+
+func TestProcessJob_CountsOnlyAppliedUpdates(t *testing.T) {
+	tests := []struct {
+		name    string
+		updated bool
+		want    float64
+	}{
+		{name: "applied update is counted", updated: true, want: 1},
+		{name: "order finalized by another worker is not counted", updated: false, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := mocks.NewMockRepository(ctrl)
+			m.EXPECT().UpdateOrderStatus(gomock.Any(), &testJob.Order, accrual.OrderStatusProcessed, money.Amount(500)).
+				Return(tt.updated, nil)
+			s := &Server{
+				Repository: m,
+				Config:     &config.Settings{},
+				Accrual: accrualstub.NewFixtureProvider(map[string]accrual.GetOrderResponse{
+					testJob.Order.OrderNumber: {Status: accrual.OrderStatusProcessed, Accrual: 500},
+				}),
+			}
+
+			processed := testutil.ToFloat64(metrics.OrdersProcessed.WithLabelValues(accrual.OrderStatusProcessed))
+			accrued := testutil.ToFloat64(metrics.BonusesAccrued)
+			processJob(context.Background(), s, testJob)
+
+			assert.Equal(t, tt.want, testutil.ToFloat64(metrics.OrdersProcessed.WithLabelValues(accrual.OrderStatusProcessed))-processed)
+			assert.Equal(t, tt.want*5, testutil.ToFloat64(metrics.BonusesAccrued)-accrued)
+		})
+	}
+}
@@ -1,37 +1,121 @@
 package accrual
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
-	"github.com/sethgrid/pester"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/metrics"
+	"github.com/arseniy96/bonus-program/internal/tracing"
 )
 
-type Client struct {
-	HTTPClient *pester.Client
+const (
+	RequestTimeout = 5 * time.Second
+	// DefaultRetryAfter используется, если сервис ответил 429 без корректного заголовка Retry-After
+	DefaultRetryAfter = time.Minute
+)
+
+var ErrOrderNotRegistered = errors.New(`order is not registered in accrual system`)
+
+// TooManyRequestsError — сервис начислений ответил 429.
+type TooManyRequestsError struct {
+	RetryAfter time.Duration
+	// Limit — разрешённое число запросов в минуту из тела ответа, 0 — если его не удалось разобрать
+	Limit int
 }
 
-func InitClient() *Client {
-	client := pester.New()
-	client.MaxRetries = 3
-	client.KeepLog = true
+func (e *TooManyRequestsError) Error() string {
+	return fmt.Sprintf("accrual rate limit exceeded (%d requests per minute), retry after %v", e.Limit, e.RetryAfter)
+}
+
+// Client безопасен для конкурентного использования, поэтому создаётся один на всех обработчиков.
+// Ограничение частоты запросов общее для всех, кто пользуется клиентом: после ответа 429 все запросы
+// приостанавливаются на время из Retry-After.
+type Client struct {
+	Host       string
+	HTTPClient *http.Client
+	limiter    *limiter
+}
 
+func NewClient(host string) *Client {
 	return &Client{
-		HTTPClient: client,
+		Host:       host,
+		HTTPClient: &http.Client{Timeout: RequestTimeout},
+		limiter:    newLimiter(),
+	}
+}
+
+// PausedFor возвращает, сколько ещё клиент не будет отправлять запросы после ответа 429.
+func (c *Client) PausedFor() time.Duration {
+	return c.limiter.pauseLeft()
+}
+
+func (c *Client) GetOrderRequest(ctx context.Context, url string) (*GetOrderResponse, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	// время ожидания в ограничителе частоты в задержку запроса не входит
+	ctx, span := tracing.Start(ctx, "accrual.GetOrder",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPMethodKey.String(http.MethodGet), semconv.HTTPURLKey.String(url)))
+	start := time.Now()
+	res, err := c.getOrder(ctx, url)
+	metrics.AccrualRequestDuration.Observe(time.Since(start).Seconds())
+	result := requestResult(err)
+	metrics.AccrualRequests.WithLabelValues(result).Inc()
+	span.SetAttributes(attribute.String("accrual.result", result))
+	if result == metrics.AccrualResultError {
+		tracing.End(span, err)
+	} else {
+		span.End()
 	}
+
+	return res, err
 }
 
-func (c *Client) GetOrderRequest(url string) (*GetOrderResponse, error) {
-	resp, err := c.HTTPClient.Get(url)
+func (c *Client) getOrder(ctx context.Context, url string) (*GetOrderResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if requestID := logger.RequestID(ctx); requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		logger.Log.Errorf("accrual request error: %v", c.HTTPClient.LogString())
+		logger.FromContext(ctx).Errorf("accrual request error: %v", err)
 		return nil, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
+	trace.SpanFromContext(ctx).SetAttributes(semconv.HTTPStatusCodeKey.Int(resp.StatusCode))
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		return nil, ErrOrderNotRegistered
+	case http.StatusTooManyRequests:
+		tooManyErr := parseTooManyRequests(resp)
+		c.limiter.pause(tooManyErr.RetryAfter)
+		c.limiter.adjust(tooManyErr.Limit)
+		logger.Log.Warnw("accrual rate limit exceeded",
+			"retry_after", tooManyErr.RetryAfter,
+			"requests_per_minute", tooManyErr.Limit)
+		return nil, tooManyErr
+	default:
 		return nil, fmt.Errorf("invalid status in accrual response: %v", resp.Status)
 	}
 
@@ -45,3 +129,39 @@ func (c *Client) GetOrderRequest(url string) (*GetOrderResponse, error) {
 
 	return &r, nil
 }
+
+func requestResult(err error) string {
+	var tooManyErr *TooManyRequestsError
+	switch {
+	case err == nil:
+		return metrics.AccrualResultOK
+	case errors.Is(err, ErrOrderNotRegistered):
+		return metrics.AccrualResultNotRegistered
+	case errors.As(err, &tooManyErr):
+		return metrics.AccrualResultTooManyRequests
+	}
+	return metrics.AccrualResultError
+}
+
+func parseTooManyRequests(resp *http.Response) *TooManyRequestsError {
+	e := &TooManyRequestsError{RetryAfter: DefaultRetryAfter}
+
+	if header := resp.Header.Get("Retry-After"); header != "" {
+		if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+			e.RetryAfter = time.Duration(seconds) * time.Second
+		} else if date, err := http.ParseTime(header); err == nil {
+			e.RetryAfter = time.Until(date)
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err == nil {
+		// No more than N requests per minute allowed
+		var limit int
+		if _, err := fmt.Sscanf(string(body), "No more than %d requests per minute allowed", &limit); err == nil && limit > 0 {
+			e.Limit = limit
+		}
+	}
+
+	return e
+}
@@ -0,0 +1,153 @@
+package accrual
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/metrics"
+)
+
+func init() {
+	logger.Log = zap.NewNop().Sugar()
+}
+
+func TestClient_CheckOrder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/orders/12345678903":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"order":"12345678903","status":"PROCESSED","accrual":729.98}`))
+		case "/api/orders/2377225624":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"order":"2377225624","status":"PROCESSING"}`))
+		case "/api/orders/4561261212345467":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name    string
+		order   string
+		want    *GetOrderResponse
+		wantErr error
+	}{
+		{
+			name:  "processed order",
+			order: "12345678903",
+			want:  &GetOrderResponse{Order: "12345678903", Status: OrderStatusProcessed, Accrual: 72998},
+		},
+		{
+			name:  "order without accrual",
+			order: "2377225624",
+			want:  &GetOrderResponse{Order: "2377225624", Status: OrderStatusProcessing},
+		},
+		{
+			name:    "order is not registered",
+			order:   "4561261212345467",
+			wantErr: ErrOrderNotRegistered,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(ts.URL)
+			got, err := c.CheckOrder(context.Background(), tt.order)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClient_CheckOrder_TooManyRequests(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte("No more than 120 requests per minute allowed"))
+	}))
+	defer ts.Close()
+
+	tooManyBefore := testutil.ToFloat64(metrics.AccrualRequests.WithLabelValues(metrics.AccrualResultTooManyRequests))
+
+	c := NewClient(ts.URL)
+	_, err := c.CheckOrder(context.Background(), "12345678903")
+
+	var tooManyErr *TooManyRequestsError
+	require.True(t, errors.As(err, &tooManyErr))
+	assert.Equal(t, time.Minute, tooManyErr.RetryAfter)
+	assert.Equal(t, 120, tooManyErr.Limit)
+	assert.Greater(t, c.PausedFor(), 50*time.Second)
+	assert.Equal(t, tooManyBefore+1, testutil.ToFloat64(metrics.AccrualRequests.WithLabelValues(metrics.AccrualResultTooManyRequests)))
+
+	// пока клиент на паузе, запросы не отправляются
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = c.CheckOrder(ctx, "12345678903")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_CheckOrder_PropagatesTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var traceParent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParent = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"order":"12345678903","status":"PROCESSED","accrual":729.98}`))
+	}))
+	defer ts.Close()
+
+	_, err := NewClient(ts.URL).CheckOrder(context.Background(), "12345678903")
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "accrual.GetOrder", spans[0].Name())
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+	assert.Contains(t, traceParent, spans[0].SpanContext().SpanID().String())
+}
+
+func TestClient_Ping(t *testing.T) {
+	status := http.StatusNotFound
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	assert.NoError(t, c.Ping(context.Background()))
+
+	status = http.StatusServiceUnavailable
+	assert.Error(t, c.Ping(context.Background()))
+
+	ts.Close()
+	assert.Error(t, c.Ping(context.Background()))
+}
@@ -1,6 +1,9 @@
 package accrual
 
-import "net/url"
+import (
+	"context"
+	"net/url"
+)
 
 const (
 	OrderStatusRegistered = "REGISTERED"
@@ -10,12 +13,11 @@ const (
 	GetOrderPath          = "/api/orders/"
 )
 
-func CheckOrder(host, orderNumber string) (*GetOrderResponse, error) {
-	fullURL, err := url.JoinPath(host, GetOrderPath, orderNumber)
+func (c *Client) CheckOrder(ctx context.Context, orderNumber string) (*GetOrderResponse, error) {
+	fullURL, err := url.JoinPath(c.Host, GetOrderPath, orderNumber)
 	if err != nil {
 		return nil, err
 	}
-	client := InitClient()
 
-	return client.GetOrderRequest(fullURL)
+	return c.GetOrderRequest(ctx, fullURL)
 }
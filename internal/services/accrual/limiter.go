@@ -0,0 +1,63 @@
+package accrual
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limiter ограничивает частоту запросов к системе начислений. Пока сервис не ответил 429, ограничения нет,
+// после этого лимит подстраивается под сообщённое им число запросов в минуту.
+type limiter struct {
+	rate *rate.Limiter
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+func newLimiter() *limiter {
+	return &limiter{
+		rate: rate.NewLimiter(rate.Inf, 1),
+	}
+}
+
+func (l *limiter) Wait(ctx context.Context) error {
+	if left := l.pauseLeft(); left > 0 {
+		timer := time.NewTimer(left)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	return l.rate.Wait(ctx)
+}
+
+func (l *limiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	until := time.Now().Add(d)
+	if until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+func (l *limiter) pauseLeft() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return time.Until(l.pausedUntil)
+}
+
+func (l *limiter) adjust(perMinute int) {
+	if perMinute <= 0 {
+		return
+	}
+	l.rate.SetLimit(rate.Limit(float64(perMinute) / time.Minute.Seconds()))
+}
@@ -1,7 +1,9 @@
 package accrual
 
+import "github.com/arseniy96/bonus-program/internal/services/money"
+
 type GetOrderResponse struct {
-	Order   string  `json:"order"`
-	Status  string  `json:"status"`
-	Accrual float64 `json:"accrual"`
+	Order   string       `json:"order"`
+	Status  string       `json:"status"`
+	Accrual money.Amount `json:"accrual"`
 }
@@ -0,0 +1,28 @@
+package accrual
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Ping проверяет, что система начислений отвечает. Запрос идёт мимо ограничителя частоты и не учитывается
+// в метриках запросов; любой ответ, кроме 5xx, означает, что сервис доступен.
+func (c *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Host, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("accrual responded with status: %v", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Package authtoken выпускает и проверяет JWT доступа. Токены подписываются HS256, в заголовке kid
+// указывается идентификатор ключа, поэтому ключи можно менять без разлогина пользователей: новый ключ
+// становится активным, а старый остаётся в списке для проверки, пока не истекут выпущенные им токены.
+package authtoken
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	Issuer = "gophermart"
+	// MinSecretLength — минимальная длина секрета HS256 в байтах
+	MinSecretLength = 32
+	ephemeralKeyID  = "ephemeral"
+)
+
+var ErrInvalidToken = errors.New(`invalid token`)
+
+// Claims — содержимое JWT доступа.
+type Claims struct {
+	jwt.RegisteredClaims
+	UserID    int    `json:"uid"`
+	Login     string `json:"login"`
+	SessionID string `json:"sid"`
+}
+
+// Keys — набор ключей подписи. Подписывает только активный ключ, проверять можно любым из набора.
+type Keys struct {
+	activeID string
+	secrets  map[string][]byte
+}
+
+// NewKeys разбирает ключи в формате «kid1:secret1,kid2:secret2». Если activeID пустой,
+// активным считается первый ключ.
+func NewKeys(spec, activeID string) (*Keys, error) {
+	k := &Keys{secrets: map[string][]byte{}}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(pair, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("signing key must be in kid:secret format")
+		}
+		if len(secret) < MinSecretLength {
+			return nil, fmt.Errorf("signing key %q is shorter than %d bytes", id, MinSecretLength)
+		}
+		if _, exists := k.secrets[id]; exists {
+			return nil, fmt.Errorf("duplicate signing key id %q", id)
+		}
+		k.secrets[id] = []byte(secret)
+		if activeID == "" {
+			activeID = id
+		}
+	}
+
+	if len(k.secrets) == 0 {
+		return nil, fmt.Errorf("no signing keys")
+	}
+	if _, ok := k.secrets[activeID]; !ok {
+		return nil, fmt.Errorf("active signing key %q not found", activeID)
+	}
+	k.activeID = activeID
+
+	return k, nil
+}
+
+// GenerateKeys создаёт случайный ключ на время жизни процесса. Токены, подписанные им,
+// перестают действовать после перезапуска и не принимаются другими репликами.
+func GenerateKeys() (*Keys, error) {
+	secret := make([]byte, MinSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	return NewKeys(ephemeralKeyID+":"+hex.EncodeToString(secret), "")
+}
+
+// Issue подписывает claims, заполняя зарегистрированные поля: токен действует ttl.
+func (k *Keys) Issue(claims Claims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    Issuer,
+		Subject:   strconv.Itoa(claims.UserID),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = k.activeID
+
+	return token.SignedString(k.secrets[k.activeID])
+}
+
+// Parse проверяет подпись и срок действия токена.
+func (k *Keys) Parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		id, _ := t.Header["kid"].(string)
+		secret, ok := k.secrets[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", id)
+		}
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if !token.Valid || !claims.VerifyIssuer(Issuer, true) || claims.UserID == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
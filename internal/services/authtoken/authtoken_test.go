@@ -0,0 +1,104 @@
+package authtoken
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	oldSecret = "old-secret-old-secret-old-secret"
+	newSecret = "new-secret-new-secret-new-secret"
+)
+
+func TestKeys_IssueAndParse(t *testing.T) {
+	keys, err := NewKeys("v1:"+oldSecret, "")
+	require.NoError(t, err)
+
+	token, err := keys.Issue(Claims{UserID: 42, Login: "gopher", SessionID: "session"}, time.Hour)
+	require.NoError(t, err)
+
+	claims, err := keys.Parse(token)
+	require.NoError(t, err)
+	assert.Equal(t, 42, claims.UserID)
+	assert.Equal(t, "gopher", claims.Login)
+	assert.Equal(t, "session", claims.SessionID)
+}
+
+func TestKeys_Rotation(t *testing.T) {
+	oldKeys, err := NewKeys("v1:"+oldSecret, "")
+	require.NoError(t, err)
+	oldToken, err := oldKeys.Issue(Claims{UserID: 1, Login: "gopher", SessionID: "session"}, time.Hour)
+	require.NoError(t, err)
+
+	// v2 стал активным, v1 оставлен для проверки уже выпущенных токенов
+	rotated, err := NewKeys("v1:"+oldSecret+",v2:"+newSecret, "v2")
+	require.NoError(t, err)
+	_, err = rotated.Parse(oldToken)
+	assert.NoError(t, err)
+
+	newToken, err := rotated.Issue(Claims{UserID: 1, Login: "gopher", SessionID: "session"}, time.Hour)
+	require.NoError(t, err)
+	_, err = oldKeys.Parse(newToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// v1 удалён из списка – его токены больше не принимаются
+	withoutOld, err := NewKeys("v2:"+newSecret, "")
+	require.NoError(t, err)
+	_, err = withoutOld.Parse(oldToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestKeys_ParseInvalid(t *testing.T) {
+	keys, err := NewKeys("v1:"+oldSecret, "")
+	require.NoError(t, err)
+
+	expired, err := keys.Issue(Claims{UserID: 1, Login: "gopher", SessionID: "session"}, -time.Minute)
+	require.NoError(t, err)
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, Claims{UserID: 1}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	valid, err := keys.Issue(Claims{UserID: 1, Login: "gopher", SessionID: "session"}, time.Hour)
+	require.NoError(t, err)
+	tampered := valid[:strings.LastIndex(valid, ".")] + ".c2lnbmF0dXJl"
+
+	for name, token := range map[string]string{
+		"expired":  expired,
+		"alg none": unsigned,
+		"tampered": tampered,
+		"garbage":  "not-a-token",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := keys.Parse(token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestNewKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		activeID string
+		wantErr  bool
+	}{
+		{name: "single key", spec: "v1:" + oldSecret},
+		{name: "explicit active key", spec: "v1:" + oldSecret + ", v2:" + newSecret, activeID: "v2"},
+		{name: "empty", spec: "", wantErr: true},
+		{name: "without id", spec: oldSecret, wantErr: true},
+		{name: "short secret", spec: "v1:short", wantErr: true},
+		{name: "duplicate id", spec: "v1:" + oldSecret + ",v1:" + newSecret, wantErr: true},
+		{name: "unknown active key", spec: "v1:" + oldSecret, activeID: "v2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeys(tt.spec, tt.activeID)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Package breaker — circuit breaker для вызовов внешних сервисов. После FailureThreshold неудач подряд
+// breaker размыкается и CoolDown сразу отклоняет вызовы, затем пропускает HalfOpenRequests пробных вызовов:
+// если они успешны, breaker замыкается, если нет — снова размыкается.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type State int
+
+const (
+	Closed State = iota
+	HalfOpen
+	Open
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case HalfOpen:
+		return "half-open"
+	case Open:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+var ErrOpen = errors.New(`circuit breaker is open`)
+
+type Config struct {
+	FailureThreshold int
+	CoolDown         time.Duration
+	HalfOpenRequests int
+}
+
+// Done сообщает breaker результат разрешённого вызова. Вызов, прерванный не по вине сервиса
+// (например, отменой контекста), нужно завершать с failed=false.
+type Done func(failed bool)
+
+type Breaker struct {
+	config        Config
+	now           func() time.Time
+	onStateChange func(from, to State)
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	// generation меняется при каждой смене состояния: результат вызова, разрешённого в прошлом поколении,
+	// не должен попасть в счётчики нового, даже если состояние с тех пор вернулось к прежнему
+	generation uint64
+	// inFlight и successes считаются только в состоянии HalfOpen
+	inFlight  int
+	successes int
+}
+
+func New(config Config) *Breaker {
+	if config.HalfOpenRequests < 1 {
+		config.HalfOpenRequests = 1
+	}
+	return &Breaker{config: config, now: time.Now}
+}
+
+// OnStateChange задаёт функцию, которая вызывается при каждой смене состояния. Её нужно задать
+// до первого вызова Allow; вызывается под блокировкой breaker, поэтому не должна обращаться к нему.
+func (b *Breaker) OnStateChange(fn func(from, to State)) {
+	b.onStateChange = fn
+}
+
+// Allow разрешает вызов или возвращает ErrOpen. После разрешённого вызова обязательно нужно вызвать Done.
+func (b *Breaker) Allow() (Done, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open {
+		if b.now().Sub(b.openedAt) < b.config.CoolDown {
+			return nil, ErrOpen
+		}
+		b.setState(HalfOpen)
+	}
+	if b.state == HalfOpen {
+		if b.inFlight >= b.config.HalfOpenRequests {
+			return nil, ErrOpen
+		}
+		b.inFlight++
+	}
+
+	generation := b.generation
+	return func(failed bool) { b.done(generation, failed) }, nil
+}
+
+func (b *Breaker) done(generation uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// результат вызова, начатого до смены состояния, уже ни на что не влияет
+	if generation != b.generation {
+		return
+	}
+	if b.state == HalfOpen {
+		b.inFlight--
+	}
+
+	switch {
+	case failed && b.state == HalfOpen:
+		b.open()
+	case failed:
+		b.failures++
+		if b.failures >= b.config.FailureThreshold {
+			b.open()
+		}
+	case b.state == HalfOpen:
+		b.successes++
+		if b.successes >= b.config.HalfOpenRequests {
+			b.setState(Closed)
+		}
+	default:
+		b.failures = 0
+	}
+}
+
+func (b *Breaker) open() {
+	b.openedAt = b.now()
+	b.setState(Open)
+}
+
+func (b *Breaker) setState(state State) {
+	from := b.state
+	b.state = state
+	b.generation++
+	b.failures = 0
+	b.inFlight = 0
+	b.successes = 0
+	if b.onStateChange != nil && from != state {
+		b.onStateChange(from, state)
+	}
+}
+
+// State возвращает текущее состояние. Разомкнутый breaker, у которого истёк CoolDown, переходит
+// в HalfOpen только при следующем Allow.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// OpenFor возвращает, сколько ещё breaker будет отклонять вызовы.
+func (b *Breaker) OpenFor() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != Open {
+		return 0
+	}
+	left := b.config.CoolDown - b.now().Sub(b.openedAt)
+	if left < 0 {
+		return 0
+	}
+	return left
+}
@@ -0,0 +1,142 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newTestBreaker(config Config) (*Breaker, *clock, *[]State) {
+	c := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b := New(config)
+	b.now = c.Now
+	var transitions []State
+	b.OnStateChange(func(_, to State) {
+		transitions = append(transitions, to)
+	})
+	return b, c, &transitions
+}
+
+func call(t *testing.T, b *Breaker, failed bool) {
+	t.Helper()
+	done, err := b.Allow()
+	require.NoError(t, err)
+	done(failed)
+}
+
+func TestBreaker_Opens(t *testing.T) {
+	b, c, transitions := newTestBreaker(Config{FailureThreshold: 3, CoolDown: time.Minute})
+
+	call(t, b, true)
+	call(t, b, true)
+	// успешный вызов сбрасывает счётчик — нужны неудачи подряд
+	call(t, b, false)
+	call(t, b, true)
+	call(t, b, true)
+	assert.Equal(t, Closed, b.State())
+
+	call(t, b, true)
+	assert.Equal(t, Open, b.State())
+	assert.Equal(t, time.Minute, b.OpenFor())
+
+	c.now = c.now.Add(20 * time.Second)
+	_, err := b.Allow()
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.Equal(t, 40*time.Second, b.OpenFor())
+	assert.Equal(t, []State{Open}, *transitions)
+}
+
+func TestBreaker_HalfOpen(t *testing.T) {
+	tests := []struct {
+		name   string
+		failed []bool
+		want   State
+	}{
+		{
+			name:   "successful trials close the circuit",
+			failed: []bool{false, false},
+			want:   Closed,
+		},
+		{
+			name:   "failed trial opens the circuit again",
+			failed: []bool{false, true},
+			want:   Open,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, c, transitions := newTestBreaker(Config{FailureThreshold: 1, CoolDown: time.Minute, HalfOpenRequests: 2})
+			call(t, b, true)
+			c.now = c.now.Add(time.Minute)
+
+			var dones []Done
+			for range tt.failed {
+				done, err := b.Allow()
+				require.NoError(t, err)
+				dones = append(dones, done)
+			}
+			assert.Equal(t, HalfOpen, b.State())
+			// пробных вызовов не больше HalfOpenRequests одновременно
+			_, err := b.Allow()
+			assert.ErrorIs(t, err, ErrOpen)
+			assert.Zero(t, b.OpenFor())
+
+			for i, done := range dones {
+				done(tt.failed[i])
+			}
+			assert.Equal(t, tt.want, b.State())
+			assert.Equal(t, []State{Open, HalfOpen, tt.want}, *transitions)
+		})
+	}
+}
+
+func TestBreaker_StaleResult(t *testing.T) {
+	b, _, _ := newTestBreaker(Config{FailureThreshold: 1, CoolDown: time.Minute})
+
+	slow, err := b.Allow()
+	require.NoError(t, err)
+	call(t, b, true)
+	require.Equal(t, Open, b.State())
+
+	// ответ на запрос, отправленный до размыкания, не замыкает breaker
+	slow(false)
+	assert.Equal(t, Open, b.State())
+}
+
+func TestBreaker_StaleHalfOpenResult(t *testing.T) {
+	b, c, transitions := newTestBreaker(Config{FailureThreshold: 1, CoolDown: time.Minute, HalfOpenRequests: 2})
+	call(t, b, true)
+	c.now = c.now.Add(time.Minute)
+
+	// два пробных вызова: второй проваливается раньше, чем завершится первый
+	slow, err := b.Allow()
+	require.NoError(t, err)
+	call(t, b, true)
+	require.Equal(t, Open, b.State())
+
+	c.now = c.now.Add(time.Minute)
+	trial, err := b.Allow()
+	require.NoError(t, err)
+	require.Equal(t, HalfOpen, b.State())
+
+	// успех из прошлого HalfOpen не освобождает место нового пробного вызова и не засчитывается
+	slow(false)
+	_, err = b.Allow()
+	require.NoError(t, err)
+	_, err = b.Allow()
+	assert.ErrorIs(t, err, ErrOpen)
+
+	trial(false)
+	assert.Equal(t, HalfOpen, b.State())
+	assert.Equal(t, []State{Open, HalfOpen, Open, HalfOpen}, *transitions)
+}
@@ -0,0 +1,33 @@
+// Package ctxutil содержит вспомогательные функции для работы с context.Context.
+package ctxutil
+
+import (
+	"context"
+	"time"
+)
+
+// WithoutCancel возвращает контекст со всеми значениями parent (ID запроса, текущий спан), который не
+// отменяется вместе с parent и не наследует его дедлайн. Аналог context.WithoutCancel из Go 1.21.
+func WithoutCancel(parent context.Context) context.Context {
+	return withoutCancel{parent: parent}
+}
+
+type withoutCancel struct {
+	parent context.Context
+}
+
+func (withoutCancel) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (withoutCancel) Done() <-chan struct{} {
+	return nil
+}
+
+func (withoutCancel) Err() error {
+	return nil
+}
+
+func (c withoutCancel) Value(key any) any {
+	return c.parent.Value(key)
+}
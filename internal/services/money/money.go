@@ -0,0 +1,190 @@
+// Package money описывает суммы баллов лояльности. Сумма хранится точно — в сотых долях балла (копейках),
+// а в JSON передаётся десятичным числом, как того требует спецификация API.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Scale — сколько копеек в одном балле.
+const Scale = 100
+
+var (
+	ErrInvalidAmount = errors.New(`invalid amount`)
+	ErrOverflow      = errors.New(`amount is out of range`)
+)
+
+// Amount — сумма баллов в копейках.
+type Amount int64
+
+// FromCents возвращает сумму, равную cents копеек.
+func FromCents(cents int64) Amount {
+	return Amount(cents)
+}
+
+const (
+	maxInputLength = 256
+	// maxExponent ограничивает порядок числа, чтобы запись вроде 1e999999999 не заставила big.Rat
+	// строить гигантское число. С учётом maxInputLength всё, что за пределами, — переполнение или ноль.
+	maxExponent = 400
+)
+
+var decimalPattern = regexp.MustCompile(`^([+-]?)(\d+(?:\.\d*)?|\.\d+)(?:[eE]([+-]?\d+))?$`)
+
+// Parse разбирает десятичную запись суммы («729.98», «500», «1e3»). Если знаков после запятой больше двух,
+// сумма округляется до копеек по правилу «половина — от нуля»: 0.005 → 0.01, -0.005 → -0.01.
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	m := decimalPattern.FindStringSubmatch(s)
+	if len(s) > maxInputLength || m == nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	isZero := strings.Trim(m[2], "0.") == ""
+	if m[3] != "" {
+		exp, err := strconv.Atoi(m[3])
+		switch {
+		case isZero:
+			return 0, nil
+		case (err != nil || exp > maxExponent) && !strings.HasPrefix(m[3], "-"):
+			return 0, fmt.Errorf("%w: %q", ErrOverflow, s)
+		case err != nil || exp < -maxExponent:
+			return 0, nil
+		}
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	r.Mul(r, big.NewRat(Scale, 1))
+	cents := roundHalfAwayFromZero(r)
+	if !cents.IsInt64() {
+		return 0, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+
+	return Amount(cents.Int64()), nil
+}
+
+func roundHalfAwayFromZero(r *big.Rat) *big.Int {
+	num := new(big.Int).Abs(r.Num())
+	den := r.Denom()
+
+	// (2*|num| + den) / (2*den) — это |r|, округлённое до ближайшего целого, половина — вверх
+	q := new(big.Int).Mul(num, big.NewInt(2))
+	q.Add(q, den)
+	q.Quo(q, new(big.Int).Mul(den, big.NewInt(2)))
+	if r.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q
+}
+
+// Cents возвращает сумму в копейках.
+func (a Amount) Cents() int64 {
+	return int64(a)
+}
+
+// Add складывает суммы и возвращает ErrOverflow при переполнении.
+func (a Amount) Add(b Amount) (Amount, error) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, ErrOverflow
+	}
+	return a + b, nil
+}
+
+// String возвращает десятичную запись суммы без лишних нулей: 72998 → «729.98», 50050 → «500.5», 500 → «5».
+func (a Amount) String() string {
+	sign := ""
+	u := uint64(a)
+	if a < 0 {
+		sign = "-"
+		u = uint64(-(a + 1)) + 1 // без переполнения для math.MinInt64
+	}
+
+	units := strconv.FormatUint(u/Scale, 10)
+	cents := u % Scale
+	switch {
+	case cents == 0:
+		return sign + units
+	case cents%10 == 0:
+		return fmt.Sprintf("%s%s.%d", sign, units, cents/10)
+	default:
+		return fmt.Sprintf("%s%s.%02d", sign, units, cents)
+	}
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON принимает только JSON-числа; строки и прочие значения считаются ошибкой.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if s == "" || !(s[0] == '-' || (s[0] >= '0' && s[0] <= '9')) {
+		return fmt.Errorf("%w: %s", ErrInvalidAmount, s)
+	}
+
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// MarshalText и UnmarshalText позволяют задавать сумму десятичной записью в YAML и флагах командной строки.
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Amount) UnmarshalText(text []byte) error {
+	v, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Value сохраняет сумму в базу в копейках.
+func (a Amount) Value() (driver.Value, error) {
+	return int64(a), nil
+}
+
+// Scan читает сумму в копейках из базы.
+func (a *Amount) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+	case int64:
+		*a = Amount(v)
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into money.Amount", src)
+	}
+	return nil
+}
+
+func (a *Amount) scanString(s string) error {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*a = Amount(v)
+	return nil
+}
@@ -0,0 +1,114 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Amount
+		wantErr error
+	}{
+		{name: "integer", input: "500", want: 50000},
+		{name: "two fraction digits", input: "729.98", want: 72998},
+		{name: "one fraction digit", input: "500.5", want: 50050},
+		{name: "cents", input: "3.99", want: 399},
+		{name: "leading dot", input: ".5", want: 50},
+		{name: "trailing dot", input: "5.", want: 500},
+		{name: "plus sign", input: "+1.25", want: 125},
+		{name: "negative", input: "-1.25", want: -125},
+		{name: "exponent", input: "1.5e3", want: 150000},
+		{name: "negative exponent", input: "15e-2", want: 15},
+		{name: "round half up", input: "0.005", want: 1},
+		{name: "round down", input: "0.00499", want: 0},
+		{name: "round half away from zero for negative", input: "-0.005", want: -1},
+		{name: "huge negative exponent", input: "1e-999999999", want: 0},
+		{name: "zero with huge exponent", input: "0e999999999", want: 0},
+		{name: "empty", input: "", wantErr: ErrInvalidAmount},
+		{name: "not a number", input: "abc", wantErr: ErrInvalidAmount},
+		{name: "fraction", input: "1/3", wantErr: ErrInvalidAmount},
+		{name: "hex", input: "0x10", wantErr: ErrInvalidAmount},
+		{name: "too big", input: "92233720368547758.08", wantErr: ErrOverflow},
+		{name: "huge exponent", input: "1e999999999", wantErr: ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAmount_String(t *testing.T) {
+	tests := []struct {
+		amount Amount
+		want   string
+	}{
+		{amount: 0, want: "0"},
+		{amount: 1, want: "0.01"},
+		{amount: 399, want: "3.99"},
+		{amount: 50050, want: "500.5"},
+		{amount: 50000, want: "500"},
+		{amount: -72998, want: "-729.98"},
+		{amount: math.MinInt64, want: "-92233720368547758.08"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.amount.String())
+		})
+	}
+}
+
+func TestAmount_JSON(t *testing.T) {
+	var body struct {
+		Sum     Amount `json:"sum"`
+		Accrual Amount `json:"accrual,omitempty"`
+	}
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"sum":729.98}`), &body))
+	assert.Equal(t, Amount(72998), body.Sum)
+
+	data, err := json.Marshal(body)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"sum":729.98}`, string(data))
+
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"sum":"729.98"}`), &body), ErrInvalidAmount)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"sum":1e30}`), &body), ErrOverflow)
+}
+
+func TestAmount_Text(t *testing.T) {
+	var a Amount
+	assert.NoError(t, a.UnmarshalText([]byte("729.98")))
+	assert.Equal(t, Amount(72998), a)
+
+	text, err := a.MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, "729.98", string(text))
+
+	assert.ErrorIs(t, a.UnmarshalText([]byte("ten")), ErrInvalidAmount)
+	// при ошибке сумма не меняется
+	assert.Equal(t, Amount(72998), a)
+}
+
+func TestAmount_Add(t *testing.T) {
+	sum, err := Amount(150).Add(250)
+	assert.NoError(t, err)
+	assert.Equal(t, Amount(400), sum)
+
+	_, err = Amount(math.MaxInt64).Add(1)
+	assert.ErrorIs(t, err, ErrOverflow)
+
+	_, err = Amount(math.MinInt64).Add(-1)
+	assert.ErrorIs(t, err, ErrOverflow)
+}
@@ -3,6 +3,7 @@ package mycrypto
 import (
 	"crypto/md5"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 )
@@ -24,3 +25,9 @@ func CreateRandomToken(size int) (string, error) {
 
 	return hex.EncodeToString(b), nil
 }
+
+// HashToken хэширует случайные токены (например, refresh) для хранения в базе. Соль не нужна:
+// у токена из CreateRandomToken достаточно энтропии, чтобы перебор был бессмысленным.
+func HashToken(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
@@ -0,0 +1,60 @@
+package mycrypto
+
+import (
+	"crypto/subtle"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HashPassword хэширует пароль bcrypt с заданной стоимостью.
+func HashPassword(password string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword сверяет пароль с хэшем из базы. Кроме bcrypt поддерживаются старые хэши HashFunc (MD5),
+// для них, как и для bcrypt-хэшей с другой стоимостью, needsRehash = true: после успешного входа
+// пароль нужно перехэшировать через HashPassword. Проверка старого хэша занимает столько же времени,
+// сколько bcrypt стоимости cost, чтобы по времени ответа нельзя было найти неперехэшированные учётные записи.
+func CheckPassword(hash, password string, cost int) (ok, needsRehash bool) {
+	if !isBcryptHash(hash) {
+		CheckDummyPassword(password, cost)
+		legacy := HashFunc(password)
+		ok = subtle.ConstantTimeCompare([]byte(legacy), []byte(hash)) == 1
+		return ok, ok
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return false, false
+	}
+	hashCost, err := bcrypt.Cost([]byte(hash))
+	return true, err != nil || hashCost != cost
+}
+
+// dummyHashes — bcrypt-хэши, с которыми CheckDummyPassword сверяет пароль, по стоимости.
+var dummyHashes sync.Map
+
+// CheckDummyPassword тратит на проверку столько же времени, сколько CheckPassword для bcrypt-хэша стоимости
+// cost, и всегда возвращает false. Вызывается, когда пользователя нет, чтобы по времени ответа нельзя было
+// узнать, какие логины существуют, и перед проверкой старых хэшей.
+func CheckDummyPassword(password string, cost int) bool {
+	hash, ok := dummyHashes.Load(cost)
+	if !ok {
+		h, err := bcrypt.GenerateFromPassword([]byte("dummy password"), cost)
+		if err != nil {
+			return false
+		}
+		hash, _ = dummyHashes.LoadOrStore(cost, h)
+	}
+	_ = bcrypt.CompareHashAndPassword(hash.([]byte), []byte(password))
+	return false
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
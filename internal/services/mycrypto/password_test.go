@@ -0,0 +1,84 @@
+package mycrypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCheckPassword(t *testing.T) {
+	bcryptHash, err := HashPassword("secret", bcrypt.MinCost)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		hash            string
+		password        string
+		cost            int
+		wantOK          bool
+		wantNeedsRehash bool
+	}{
+		{
+			name:     "valid bcrypt password",
+			hash:     bcryptHash,
+			password: "secret",
+			cost:     bcrypt.MinCost,
+			wantOK:   true,
+		},
+		{
+			name:     "invalid bcrypt password",
+			hash:     bcryptHash,
+			password: "wrong",
+			cost:     bcrypt.MinCost,
+		},
+		{
+			name:            "bcrypt cost was changed",
+			hash:            bcryptHash,
+			password:        "secret",
+			cost:            bcrypt.MinCost + 1,
+			wantOK:          true,
+			wantNeedsRehash: true,
+		},
+		{
+			name:            "valid legacy password",
+			hash:            HashFunc("secret"),
+			password:        "secret",
+			cost:            bcrypt.MinCost,
+			wantOK:          true,
+			wantNeedsRehash: true,
+		},
+		{
+			name:     "invalid legacy password",
+			hash:     HashFunc("secret"),
+			password: "wrong",
+			cost:     bcrypt.MinCost,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash := CheckPassword(tt.hash, tt.password, tt.cost)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantNeedsRehash, needsRehash)
+		})
+	}
+}
+
+func TestCheckDummyPassword(t *testing.T) {
+	assert.False(t, CheckDummyPassword("dummy password", bcrypt.MinCost))
+	assert.False(t, CheckDummyPassword("secret", bcrypt.MinCost))
+
+	hash, ok := dummyHashes.Load(bcrypt.MinCost)
+	require.True(t, ok)
+	// старый хэш тоже проверяется через bcrypt той же стоимости
+	_, ok = dummyHashes.Load(bcrypt.MinCost + 1)
+	require.False(t, ok)
+	CheckPassword(HashFunc("secret"), "secret", bcrypt.MinCost+1)
+	_, ok = dummyHashes.Load(bcrypt.MinCost + 1)
+	assert.True(t, ok)
+	// сравнение идёт с хэшем той же стоимости, что и у настоящих паролей
+	cost, err := bcrypt.Cost(hash.([]byte))
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost, cost)
+}
@@ -0,0 +1,68 @@
+// Package ratelimit ограничивает частоту запросов так же, как внешняя система начислений: запросы
+// считаются в окне длиной в минуту, после исчерпания лимита клиент ждёт конца окна.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const window = time.Minute
+
+type Limiter struct {
+	limit int
+
+	mu          sync.Mutex
+	windowStart time.Time
+	count       int
+}
+
+// New возвращает ограничитель на limit запросов в минуту, 0 — без ограничения.
+func New(limit int) *Limiter {
+	return &Limiter{limit: limit}
+}
+
+func (l *Limiter) Limit() int {
+	return l.limit
+}
+
+// Limited учитывает запрос и возвращает true, если лимит исчерпан, и сколько ждать до конца окна.
+func (l *Limiter) Limited(now time.Time) (time.Duration, bool) {
+	if l.limit <= 0 {
+		return 0, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.windowStart) >= window {
+		l.windowStart = now
+		l.count = 0
+	}
+	l.count++
+	if l.count <= l.limit {
+		return 0, false
+	}
+	// округляем вверх, чтобы клиент не пришёл раньше конца окна
+	return (l.windowStart.Add(window).Sub(now) + time.Second - 1).Truncate(time.Second), true
+}
+
+// RetryAfterSeconds переводит ожидание в значение заголовка Retry-After: секунды округляются вверх,
+// и значение не меньше 1, чтобы клиент не повторял запрос сразу.
+func RetryAfterSeconds(d time.Duration) int {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// Reset начинает новое окно.
+func (l *Limiter) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.windowStart = time.Time{}
+	l.count = 0
+}
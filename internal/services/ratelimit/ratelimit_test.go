@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Limited(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(2)
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		retryAfter time.Duration
+		limited    bool
+	}{
+		{name: "first request", now: start},
+		{name: "second request", now: start.Add(10 * time.Second)},
+		{name: "over the limit", now: start.Add(20*time.Second + 500*time.Millisecond), retryAfter: 40 * time.Second, limited: true},
+		{name: "still limited", now: start.Add(59 * time.Second), retryAfter: time.Second, limited: true},
+		{name: "next window", now: start.Add(time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retryAfter, limited := l.Limited(tt.now)
+			assert.Equal(t, tt.limited, limited)
+			assert.Equal(t, tt.retryAfter, retryAfter)
+		})
+	}
+
+	l.Reset()
+	_, limited := l.Limited(start.Add(time.Minute + time.Second))
+	assert.False(t, limited)
+}
+
+func TestLimiter_Unlimited(t *testing.T) {
+	l := New(0)
+	now := time.Now()
+	for i := 0; i < 1000; i++ {
+		_, limited := l.Limited(now)
+		assert.False(t, limited)
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	assert.Equal(t, 1, RetryAfterSeconds(0))
+	assert.Equal(t, 1, RetryAfterSeconds(300*time.Millisecond))
+	assert.Equal(t, 2, RetryAfterSeconds(1500*time.Millisecond))
+	assert.Equal(t, 40, RetryAfterSeconds(40*time.Second))
+}
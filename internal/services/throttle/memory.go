@@ -0,0 +1,131 @@
+package throttle
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sweepEvery — через сколько вызовов AddFailure из памяти удаляются устаревшие ключи.
+const sweepEvery = 1000
+
+type memoryEntry struct {
+	failures    []time.Time
+	lockouts    int
+	lockedUntil time.Time
+	// expiresAt — после этого момента запись ни на что не влияет и её можно удалить
+	expiresAt time.Time
+}
+
+// MemoryStore хранит состояние в памяти процесса. Реплики с таким хранилищем считают попытки независимо.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	calls   int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]*memoryEntry),
+	}
+}
+
+func (m *MemoryStore) AddFailure(_ context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls++
+	if m.calls%sweepEvery == 0 {
+		m.sweep(now)
+	}
+
+	e := m.entry(key)
+	from := now.Add(-window)
+	kept := e.failures[:0]
+	for _, f := range e.failures {
+		if f.After(from) {
+			kept = append(kept, f)
+		}
+	}
+	e.failures = append(kept, now)
+	e.extend(now.Add(window))
+
+	return len(e.failures), nil
+}
+
+func (m *MemoryStore) Lock(_ context.Context, key string, now time.Time, forgetAfter time.Duration, lockout func(n int) time.Duration) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := m.entry(key)
+	if e.lockedUntil.After(now) {
+		return e.lockedUntil, nil
+	}
+	if now.Sub(e.lockedUntil) > forgetAfter {
+		e.lockouts = 0
+	}
+	e.lockouts++
+	e.lockedUntil = now.Add(lockout(e.lockouts))
+	e.failures = nil
+	e.extend(e.lockedUntil.Add(forgetAfter))
+
+	return e.lockedUntil, nil
+}
+
+func (m *MemoryStore) LockedUntil(_ context.Context, key string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.entries[key]; ok {
+		return e.lockedUntil, nil
+	}
+	return time.Time{}, nil
+}
+
+func (m *MemoryStore) Reset(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+	return nil
+}
+
+// DeleteExpired удаляет записи, срок которых истёк. Срок записи уже учитывает window и forgetAfter,
+// с которыми она обновлялась, поэтому параметры не нужны.
+func (m *MemoryStore) DeleteExpired(_ context.Context, prefix string, now time.Time, _, _ time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleted := 0
+	for key, e := range m.entries {
+		if strings.HasPrefix(key, prefix) && e.expiresAt.Before(now) {
+			delete(m.entries, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *MemoryStore) entry(key string) *memoryEntry {
+	e, ok := m.entries[key]
+	if !ok {
+		e = &memoryEntry{}
+		m.entries[key] = e
+	}
+	return e
+}
+
+func (m *MemoryStore) sweep(now time.Time) {
+	for key, e := range m.entries {
+		if e.expiresAt.Before(now) {
+			delete(m.entries, key)
+		}
+	}
+}
+
+func (e *memoryEntry) extend(t time.Time) {
+	if t.After(e.expiresAt) {
+		e.expiresAt = t
+	}
+}
@@ -0,0 +1,117 @@
+// Package throttle ограничивает число неудачных попыток (например, входа) по произвольному ключу:
+// считает неудачи в скользящем окне и после превышения лимита блокирует ключ, каждый раз вдвое дольше.
+package throttle
+
+import (
+	"context"
+	"time"
+)
+
+// Store хранит счётчики неудач и блокировки. Реализация в памяти подходит для одного экземпляра сервиса,
+// Postgres-реализация (store.ThrottleStore) позволяет разделить состояние между репликами.
+type Store interface {
+	// AddFailure регистрирует неудачу и возвращает число неудач по ключу за последние window.
+	AddFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error)
+	// Lock блокирует ключ и сбрасывает его счётчик неудач. Номер блокировки растёт с каждой следующей
+	// и сбрасывается, если с окончания предыдущей прошло больше forgetAfter. Если ключ уже заблокирован,
+	// блокировка не продлевается. Возвращает момент окончания блокировки.
+	Lock(ctx context.Context, key string, now time.Time, forgetAfter time.Duration, lockout func(n int) time.Duration) (time.Time, error)
+	// LockedUntil возвращает момент окончания блокировки ключа или нулевое время, если блокировок не было.
+	LockedUntil(ctx context.Context, key string) (time.Time, error)
+	// Reset забывает неудачи и блокировки ключа.
+	Reset(ctx context.Context, key string) error
+	// DeleteExpired удаляет у ключей с префиксом prefix неудачи старше window и блокировки, закончившиеся
+	// больше forgetAfter назад, — они уже ни на что не влияют. Возвращает число удалённых записей.
+	DeleteExpired(ctx context.Context, prefix string, now time.Time, window, forgetAfter time.Duration) (int, error)
+}
+
+type Config struct {
+	// MaxFailures — сколько неудач в окне допускается до блокировки; 0 отключает ограничение
+	MaxFailures int
+	Window      time.Duration
+	// BaseLockout — длительность первой блокировки, каждая следующая вдвое дольше, но не больше MaxLockout
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+// Lockout возвращает длительность n-й подряд блокировки.
+func (c Config) Lockout(n int) time.Duration {
+	d := c.BaseLockout
+	for i := 1; i < n && d < c.MaxLockout; i++ {
+		d *= 2
+	}
+	if d > c.MaxLockout {
+		d = c.MaxLockout
+	}
+	return d
+}
+
+type Throttler struct {
+	store  Store
+	prefix string
+	config Config
+	now    func() time.Time
+}
+
+// New создаёт ограничитель. prefix отделяет его ключи от ключей других ограничителей в том же хранилище.
+func New(store Store, prefix string, config Config) *Throttler {
+	return &Throttler{
+		store:  store,
+		prefix: prefix,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Check возвращает, сколько ещё продлится блокировка ключа, или 0, если он не заблокирован.
+func (t *Throttler) Check(ctx context.Context, key string) (time.Duration, error) {
+	if t.config.MaxFailures <= 0 {
+		return 0, nil
+	}
+	until, err := t.store.LockedUntil(ctx, t.prefix+key)
+	if err != nil {
+		return 0, err
+	}
+	return left(until, t.now()), nil
+}
+
+// Fail регистрирует неудачу. Если она превысила лимит, ключ блокируется и возвращается длительность блокировки.
+func (t *Throttler) Fail(ctx context.Context, key string) (time.Duration, error) {
+	if t.config.MaxFailures <= 0 {
+		return 0, nil
+	}
+	now := t.now()
+	failures, err := t.store.AddFailure(ctx, t.prefix+key, now, t.config.Window)
+	if err != nil {
+		return 0, err
+	}
+	if failures < t.config.MaxFailures {
+		return 0, nil
+	}
+
+	until, err := t.store.Lock(ctx, t.prefix+key, now, t.config.MaxLockout, t.config.Lockout)
+	if err != nil {
+		return 0, err
+	}
+	return left(until, now), nil
+}
+
+// Reset снимает с ключа неудачи и блокировки, например после успешного входа.
+func (t *Throttler) Reset(ctx context.Context, key string) error {
+	if t.config.MaxFailures <= 0 {
+		return nil
+	}
+	return t.store.Reset(ctx, t.prefix+key)
+}
+
+// DeleteExpired удаляет из хранилища неудачи и блокировки ограничителя, которые уже ни на что не влияют.
+func (t *Throttler) DeleteExpired(ctx context.Context) (int, error) {
+	return t.store.DeleteExpired(ctx, t.prefix, t.now(), t.config.Window, t.config.MaxLockout)
+}
+
+func left(until, now time.Time) time.Duration {
+	if until.After(now) {
+		return until.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,170 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newTestThrottler(config Config) (*Throttler, *clock) {
+	c := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	t := New(NewMemoryStore(), "login:", config)
+	t.now = c.Now
+	return t, c
+}
+
+var testConfig = Config{
+	MaxFailures: 3,
+	Window:      time.Minute,
+	BaseLockout: time.Minute,
+	MaxLockout:  5 * time.Minute,
+}
+
+func TestConfig_Lockout(t *testing.T) {
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{n: 1, want: time.Minute},
+		{n: 2, want: 2 * time.Minute},
+		{n: 3, want: 4 * time.Minute},
+		{n: 4, want: 5 * time.Minute},
+		{n: 100, want: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, testConfig.Lockout(tt.n), "lockout #%d", tt.n)
+	}
+}
+
+func TestThrottler_Fail(t *testing.T) {
+	ctx := context.Background()
+	th, c := newTestThrottler(testConfig)
+
+	for i := 0; i < 2; i++ {
+		lock, err := th.Fail(ctx, "gopher")
+		require.NoError(t, err)
+		assert.Zero(t, lock)
+	}
+	lock, err := th.Fail(ctx, "gopher")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, lock)
+
+	c.now = c.now.Add(20 * time.Second)
+	left, err := th.Check(ctx, "gopher")
+	require.NoError(t, err)
+	assert.Equal(t, 40*time.Second, left)
+
+	// другой ключ не заблокирован
+	left, err = th.Check(ctx, "other")
+	require.NoError(t, err)
+	assert.Zero(t, left)
+
+	// после окончания блокировки следующая наступает быстрее и длится вдвое дольше
+	c.now = c.now.Add(time.Minute)
+	left, err = th.Check(ctx, "gopher")
+	require.NoError(t, err)
+	assert.Zero(t, left)
+	for i := 0; i < 2; i++ {
+		_, err = th.Fail(ctx, "gopher")
+		require.NoError(t, err)
+	}
+	lock, err = th.Fail(ctx, "gopher")
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, lock)
+}
+
+func TestThrottler_SlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	th, c := newTestThrottler(testConfig)
+
+	for i := 0; i < 5; i++ {
+		lock, err := th.Fail(ctx, "gopher")
+		require.NoError(t, err)
+		assert.Zero(t, lock, "attempt %d", i)
+		c.now = c.now.Add(31 * time.Second)
+	}
+}
+
+func TestThrottler_Reset(t *testing.T) {
+	ctx := context.Background()
+	th, c := newTestThrottler(testConfig)
+
+	for i := 0; i < 3; i++ {
+		_, err := th.Fail(ctx, "gopher")
+		require.NoError(t, err)
+	}
+	require.NoError(t, th.Reset(ctx, "gopher"))
+
+	left, err := th.Check(ctx, "gopher")
+	require.NoError(t, err)
+	assert.Zero(t, left)
+
+	// счётчик блокировок тоже сброшен
+	for i := 0; i < 3; i++ {
+		c.now = c.now.Add(time.Second)
+		_, err = th.Fail(ctx, "gopher")
+		require.NoError(t, err)
+	}
+	left, err = th.Check(ctx, "gopher")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, left)
+}
+
+func TestThrottler_Disabled(t *testing.T) {
+	ctx := context.Background()
+	th, _ := newTestThrottler(Config{})
+
+	for i := 0; i < 10; i++ {
+		lock, err := th.Fail(ctx, "gopher")
+		require.NoError(t, err)
+		assert.Zero(t, lock)
+	}
+}
+
+func TestThrottler_DeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	th, c := newTestThrottler(testConfig)
+	other := New(th.store, "ip:", testConfig)
+	other.now = c.Now
+
+	_, err := th.Fail(ctx, "gopher")
+	require.NoError(t, err)
+	_, err = other.Fail(ctx, "127.0.0.1")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = th.Fail(ctx, "locked")
+		require.NoError(t, err)
+	}
+
+	// неудача ещё в окне
+	deleted, err := th.DeleteExpired(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	// окно неудачи прошло, а блокировка закончилась, но её номер ещё помнится MaxLockout
+	c.now = c.now.Add(2 * time.Minute)
+	deleted, err = th.DeleteExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	c.now = c.now.Add(testConfig.MaxLockout)
+	deleted, err = th.DeleteExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	// ключи другого ограничителя удаляет он сам
+	deleted, err = other.DeleteExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+}
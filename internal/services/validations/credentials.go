@@ -0,0 +1,98 @@
+package validations
+
+import (
+	"fmt"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	LoginMinLength = 3
+	LoginMaxLength = 64
+	// PasswordMaxBytes — bcrypt учитывает только первые 72 байта пароля, остальное молча отбрасывается
+	PasswordMaxBytes = 72
+)
+
+var loginPattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// PasswordPolicy задаёт минимальные требования к паролю при регистрации.
+type PasswordPolicy struct {
+	MinLength int
+	// MinClasses — сколько разных классов символов (строчные, заглавные, цифры, прочие) должно быть в пароле
+	MinClasses int
+}
+
+// ValidateSignUp проверяет логин и пароль нового пользователя.
+func ValidateSignUp(login, password string, policy PasswordPolicy) error {
+	var errs Errors
+	errs = validateLogin(errs, login)
+	errs = validatePassword(errs, password, policy)
+	return errs.errOrNil()
+}
+
+// ValidateLogin проверяет данные для входа. Политику паролей и ограничение PasswordMaxBytes здесь
+// не применяем: политика могла ужесточиться уже после регистрации пользователя, а у старых учётных записей
+// с MD5-хэшем пароль может быть длиннее, чем допускает bcrypt.
+func ValidateLogin(login, password string) error {
+	var errs Errors
+	if login == "" {
+		errs = errs.add("login", "must not be empty")
+	} else if utf8.RuneCountInString(login) > LoginMaxLength {
+		errs = errs.add("login", fmt.Sprintf("must be at most %d characters", LoginMaxLength))
+	}
+	if password == "" {
+		errs = errs.add("password", "must not be empty")
+	}
+	return errs.errOrNil()
+}
+
+func validateLogin(errs Errors, login string) Errors {
+	switch n := utf8.RuneCountInString(login); {
+	case n == 0:
+		return errs.add("login", "must not be empty")
+	case n < LoginMinLength || n > LoginMaxLength:
+		return errs.add("login", fmt.Sprintf("must be between %d and %d characters", LoginMinLength, LoginMaxLength))
+	case !loginPattern.MatchString(login):
+		return errs.add("login", "may contain only latin letters, digits, '.', '_' and '-'")
+	}
+	return errs
+}
+
+func validatePassword(errs Errors, password string, policy PasswordPolicy) Errors {
+	switch {
+	case password == "":
+		return errs.add("password", "must not be empty")
+	case utf8.RuneCountInString(password) < policy.MinLength:
+		return errs.add("password", fmt.Sprintf("must be at least %d characters", policy.MinLength))
+	case len(password) > PasswordMaxBytes:
+		return errs.add("password", fmt.Sprintf("must be at most %d bytes", PasswordMaxBytes))
+	case passwordClasses(password) < policy.MinClasses:
+		return errs.add("password", fmt.Sprintf("must contain at least %d of: lowercase letters, uppercase letters, digits, other symbols", policy.MinClasses))
+	}
+	return errs
+}
+
+func passwordClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	classes := 0
+	for _, ok := range []bool{lower, upper, digit, other} {
+		if ok {
+			classes++
+		}
+	}
+	return classes
+}
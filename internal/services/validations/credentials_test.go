@@ -0,0 +1,135 @@
+package validations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSignUp(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 8, MinClasses: 2}
+
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		want     Errors
+	}{
+		{
+			name:     "valid",
+			login:    "gopher.go-1_0",
+			password: "secret123",
+		},
+		{
+			name:     "empty",
+			login:    "",
+			password: "",
+			want: Errors{
+				{Field: "login", Message: "must not be empty"},
+				{Field: "password", Message: "must not be empty"},
+			},
+		},
+		{
+			name:     "short login",
+			login:    "go",
+			password: "secret123",
+			want:     Errors{{Field: "login", Message: "must be between 3 and 64 characters"}},
+		},
+		{
+			name:     "long login",
+			login:    strings.Repeat("a", 65),
+			password: "secret123",
+			want:     Errors{{Field: "login", Message: "must be between 3 and 64 characters"}},
+		},
+		{
+			name:     "login charset",
+			login:    "гофер",
+			password: "secret123",
+			want:     Errors{{Field: "login", Message: "may contain only latin letters, digits, '.', '_' and '-'"}},
+		},
+		{
+			name:     "login with spaces",
+			login:    "go pher",
+			password: "secret123",
+			want:     Errors{{Field: "login", Message: "may contain only latin letters, digits, '.', '_' and '-'"}},
+		},
+		{
+			name:     "short password",
+			login:    "gopher",
+			password: "sec123",
+			want:     Errors{{Field: "password", Message: "must be at least 8 characters"}},
+		},
+		{
+			name:     "weak password",
+			login:    "gopher",
+			password: "secretsecret",
+			want:     Errors{{Field: "password", Message: "must contain at least 2 of: lowercase letters, uppercase letters, digits, other symbols"}},
+		},
+		{
+			name:     "password longer than bcrypt limit",
+			login:    "gopher",
+			password: strings.Repeat("ab1", 25),
+			want:     Errors{{Field: "password", Message: "must be at most 72 bytes"}},
+		},
+		{
+			name:     "multibyte password within limit",
+			login:    "gopher",
+			password: "пароль-пароль",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSignUp(tt.login, tt.password, policy)
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tt.want, err)
+		})
+	}
+}
+
+func TestValidateLogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		want     Errors
+	}{
+		{
+			name:     "valid",
+			login:    "gopher",
+			password: "any",
+		},
+		{
+			name: "empty",
+			want: Errors{
+				{Field: "login", Message: "must not be empty"},
+				{Field: "password", Message: "must not be empty"},
+			},
+		},
+		{
+			name:     "too long",
+			login:    strings.Repeat("a", 65),
+			password: strings.Repeat("a", 73),
+			want:     Errors{{Field: "login", Message: "must be at most 64 characters"}},
+		},
+		{
+			// у старых учётных записей с MD5-хэшем пароль может быть длиннее предела bcrypt
+			name:     "password longer than bcrypt limit",
+			login:    "gopher",
+			password: strings.Repeat("a", 100),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLogin(tt.login, tt.password)
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tt.want, err)
+		})
+	}
+}
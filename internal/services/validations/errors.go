@@ -0,0 +1,33 @@
+package validations
+
+import "strings"
+
+// FieldError описывает ошибку в одном поле запроса.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors — набор ошибок по полям. Возвращается валидаторами как error, чтобы обработчик мог
+// отдать клиенту все проблемы сразу.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, 0, len(e))
+	for _, fe := range e {
+		parts = append(parts, fe.Field+": "+fe.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (e Errors) add(field, message string) Errors {
+	return append(e, FieldError{Field: field, Message: message})
+}
+
+// errOrNil нужен, чтобы пустой набор ошибок не превращался в ненулевой error.
+func (e Errors) errOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
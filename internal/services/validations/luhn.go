@@ -1,7 +1,17 @@
 package validations
 
-import "github.com/ShiraazMoollatjie/goluhn"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ShiraazMoollatjie/goluhn"
+)
+
+var ErrInvalidOrderNumber = errors.New("invalid order number")
 
 func LuhnValidate(str string) error {
-	return goluhn.Validate(str)
+	if err := goluhn.Validate(str); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOrderNumber, err)
+	}
+	return nil
 }
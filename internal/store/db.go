@@ -5,36 +5,49 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io/fs"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 
+	"github.com/arseniy96/bonus-program/db/migrations"
 	"github.com/arseniy96/bonus-program/internal/logger"
+	"github.com/arseniy96/bonus-program/internal/services/money"
+	"github.com/arseniy96/bonus-program/internal/tracing"
 )
 
 var ErrConflict = errors.New(`already exists`)
 var ErrNowRows = errors.New(`missing data`)
+var ErrInsufficientFunds = errors.New(`insufficient funds`)
+var ErrRefreshTokenReused = errors.New(`refresh token reused`)
 
 type Database struct {
 	DB *sqlx.DB
+	// migrationVersion — последняя версия среди встроенных в бинарник миграций
+	migrationVersion uint
 }
 
 func NewStore(dsn string) (*Database, error) {
+	version, err := latestMigration()
+	if err != nil {
+		return nil, err
+	}
 	if err := runMigrations(dsn); err != nil {
 		return nil, fmt.Errorf("migrations failed with error: %w", err)
 	}
-	db, err := sqlx.Open("pgx", dsn)
+	db, err := open(dsn)
 	if err != nil {
 		return nil, err
 	}
 	database := &Database{
-		DB: db,
+		DB:               db,
+		migrationVersion: version,
 	}
 	logger.Log.Info("Database connection was created")
 
@@ -46,11 +59,15 @@ func (db *Database) Close() error {
 }
 
 func runMigrations(dsn string) error {
-	const migrationsPath = "db/migrations"
-	m, err := migrate.New(fmt.Sprintf("file://%s", migrationsPath), dsn)
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+	m, err := migrate.NewWithSourceInstance("iofs", src, dsn)
 	if err != nil {
 		return fmt.Errorf("failed to get a new migrate instance: %w", err)
 	}
+	defer m.Close()
 	if err := m.Up(); err != nil {
 		if !errors.Is(err, migrate.ErrNoChange) {
 			return fmt.Errorf("failed to apply migrations: %w", err)
@@ -59,31 +76,79 @@ func runMigrations(dsn string) error {
 	return nil
 }
 
-func (db *Database) CreateUser(ctx context.Context, login, password string) error {
-	_, err := db.DB.ExecContext(ctx, `INSERT INTO users(login, password) VALUES($1, $2)`, login, password)
+// latestMigration возвращает версию последней встроенной миграции — до неё должна быть обновлена база.
+func latestMigration() (uint, error) {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read migrations: %w", err)
+		}
+		version = next
+	}
+}
+
+func (db *Database) Ping(ctx context.Context) error {
+	return db.DB.PingContext(ctx)
+}
+
+// MigrationStatus сравнивает текущую версию схемы с последней миграцией, встроенной в бинарник.
+func (db *Database) MigrationStatus(ctx context.Context) (*MigrationStatus, error) {
+	status := MigrationStatus{Expected: db.migrationVersion}
+	err := db.DB.QueryRowContext(ctx,
+		`SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&status.Version, &status.Dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNowRows
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+func (db *Database) CreateUser(ctx context.Context, login, password string) (*User, error) {
+	u := User{Login: login, Password: password}
+	err := db.DB.QueryRowContext(ctx,
+		`INSERT INTO users(login, password) VALUES($1, $2) RETURNING id, bonuses`,
+		login, password).Scan(&u.ID, &u.Bonuses)
 
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) && pgerrcode.IsIntegrityConstraintViolation(pgErr.Code) {
-		return ErrConflict
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return err
+	return &u, nil
 }
 
-func (db *Database) UpdateUserToken(ctx context.Context, login, token string, tokenExp time.Time) error {
+func (db *Database) UpdateUserPassword(ctx context.Context, userID int, password string) error {
 	_, err := db.DB.ExecContext(ctx,
-		`UPDATE users SET token=$1, token_exp_at=$2 WHERE login=$3`,
-		token,
-		tokenExp,
-		login)
+		`UPDATE users SET password=$1 WHERE id=$2`,
+		password,
+		userID)
 	return err
 }
 
 func (db *Database) FindUserByLogin(ctx context.Context, login string) (*User, error) {
 	var u User
 	err := db.DB.QueryRowContext(ctx,
-		`SELECT id, login, password, token, bonuses, token_exp_at FROM users WHERE login=$1 LIMIT(1)`,
-		login).Scan(&u.ID, &u.Login, &u.Password, &u.Token, &u.Bonuses, &u.TokenExpAt)
+		`SELECT id, login, password, bonuses FROM users WHERE login=$1 LIMIT(1)`,
+		login).Scan(&u.ID, &u.Login, &u.Password, &u.Bonuses)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNowRows
@@ -94,11 +159,11 @@ func (db *Database) FindUserByLogin(ctx context.Context, login string) (*User, e
 	return &u, nil
 }
 
-func (db *Database) FindUserByToken(ctx context.Context, token string) (*User, error) {
+func (db *Database) FindUserByID(ctx context.Context, userID int) (*User, error) {
 	var u User
 	err := db.DB.QueryRowContext(ctx,
-		`SELECT id, login, password, token, bonuses, token_exp_at FROM users WHERE token=$1 LIMIT(1)`,
-		token).Scan(&u.ID, &u.Login, &u.Password, &u.Token, &u.Bonuses, &u.TokenExpAt)
+		`SELECT id, login, password, bonuses FROM users WHERE id=$1 LIMIT(1)`,
+		userID).Scan(&u.ID, &u.Login, &u.Password, &u.Bonuses)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNowRows
@@ -192,28 +257,219 @@ func (db *Database) FindOrderByOrderNumber(ctx context.Context, orderNumber stri
 	return &order, nil
 }
 
+// CreateOrder сохраняет заказ и в той же транзакции ставит его в очередь на опрос системы начислений.
+// В задаче запоминаются ID запроса и спан из ctx, чтобы логи и трассу обработчика можно было связать
+// с загрузкой заказа.
 func (db *Database) CreateOrder(ctx context.Context, userID int, orderNumber, status string) (*Order, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	var order Order
-	err := db.DB.QueryRowContext(ctx,
+	err = tx.QueryRowContext(ctx,
 		`INSERT INTO orders(user_id, order_number, status) VALUES($1, $2, $3) RETURNING id, order_number, status, user_id, created_at`,
 		userID, orderNumber, status).Scan(&order.ID, &order.OrderNumber, &order.Status, &order.UserID, &order.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO order_jobs(order_id, request_id, trace_parent) VALUES($1, NULLIF($2, ''), NULLIF($3, ''))`,
+		order.ID, logger.RequestID(ctx), tracing.TraceParent(ctx))
+	if err != nil {
+		return nil, err
+	}
 
-	return &order, err
+	return &order, tx.Commit()
 }
 
-func (db *Database) UpdateOrderStatus(ctx context.Context, order *Order, status string, bonus int) error {
-	tx, err := db.DB.Begin()
+// ClaimOrderJobs забирает в работу задачи, время которых подошло. Задачи в dead letter не забираются. Строки блокируются через SKIP LOCKED,
+// поэтому несколько реплик не получат одну и ту же задачу. Взятой задаче сдвигается next_attempt_at на lease:
+// если обработчик упадёт, задача снова станет доступна по истечении этого времени.
+func (db *Database) ClaimOrderJobs(ctx context.Context, limit int, lease time.Duration) ([]OrderJob, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer tx.Rollback()
 
-	_, err = tx.ExecContext(ctx,
-		`UPDATE orders SET status=$1 WHERE id=$2`,
-		status, order.ID)
+	rows, err := tx.QueryContext(ctx,
+		`SELECT j.id, j.attempts, j.next_attempt_at, COALESCE(j.last_error, ''), COALESCE(j.request_id, ''), COALESCE(j.trace_parent, ''), j.created_at, j.enqueued_at, o.id, o.order_number, o.status, o.user_id, o.created_at
+		FROM order_jobs j JOIN orders o ON j.order_id=o.id
+		WHERE j.next_attempt_at <= CURRENT_TIMESTAMP AND j.dead_lettered_at IS NULL
+		ORDER BY j.next_attempt_at
+		LIMIT $1
+		FOR UPDATE OF j SKIP LOCKED`,
+		limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []OrderJob
+	for rows.Next() {
+		var job OrderJob
+		err = rows.Scan(&job.ID, &job.Attempts, &job.NextAttemptAt, &job.LastError, &job.RequestID, &job.TraceParent, &job.CreatedAt, &job.EnqueuedAt,
+			&job.Order.ID, &job.Order.OrderNumber, &job.Order.Status, &job.Order.UserID, &job.Order.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range jobs {
+		err = tx.QueryRowContext(ctx,
+			`UPDATE order_jobs SET attempts=attempts+1, next_attempt_at=CURRENT_TIMESTAMP + make_interval(secs => $1), updated_at=CURRENT_TIMESTAMP
+			WHERE id=$2 RETURNING attempts, next_attempt_at`,
+			lease.Seconds(), jobs[i].ID).Scan(&jobs[i].Attempts, &jobs[i].NextAttemptAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return jobs, tx.Commit()
+}
+
+// RecoverOrderJobs ставит в очередь незавершённые заказы с id больше afterID, у которых нет задачи.
+// Заказы перебираются страницами по limit штук в порядке id, чтобы не держать в памяти всю таблицу.
+func (db *Database) RecoverOrderJobs(ctx context.Context, afterID, limit int) (*RecoveryPage, error) {
+	var page RecoveryPage
+	err := db.DB.QueryRowContext(ctx,
+		`WITH page AS (
+			SELECT id FROM orders WHERE id > $1 AND status IN ($2, $3) ORDER BY id LIMIT $4
+		), inserted AS (
+			INSERT INTO order_jobs(order_id) SELECT id FROM page ON CONFLICT (order_id) DO NOTHING RETURNING order_id
+		)
+		SELECT COALESCE(MAX(page.id), 0), COUNT(page.id), (SELECT COUNT(*) FROM inserted) FROM page`,
+		afterID, OrderStatusNew, OrderStatusProcessing, limit).Scan(&page.LastID, &page.Scanned, &page.Enqueued)
+	if err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// RescheduleOrderJob откладывает следующую попытку опроса заказа на delay и запоминает последнюю ошибку.
+func (db *Database) RescheduleOrderJob(ctx context.Context, jobID int, delay time.Duration, lastError string) error {
+	_, err := db.DB.ExecContext(ctx,
+		`UPDATE order_jobs SET next_attempt_at=CURRENT_TIMESTAMP + make_interval(secs => $1), last_error=NULLIF($2, ''), updated_at=CURRENT_TIMESTAMP
+		WHERE id=$3`,
+		delay.Seconds(), lastError, jobID)
+	return err
+}
+
+// ReleaseOrderJob возвращает задачу в очередь через delay, не засчитывая попытку: заказ не опрашивали
+// по причинам, от него не зависящим (система начислений просит подождать, сервис останавливается,
+// не удалось записать результат в базу).
+func (db *Database) ReleaseOrderJob(ctx context.Context, jobID int, delay time.Duration, lastError string) error {
+	_, err := db.DB.ExecContext(ctx,
+		`UPDATE order_jobs SET attempts=GREATEST(attempts-1, 0), next_attempt_at=CURRENT_TIMESTAMP + make_interval(secs => $1),
+			last_error=COALESCE(NULLIF($2, ''), last_error), updated_at=CURRENT_TIMESTAMP
+		WHERE id=$3`,
+		delay.Seconds(), lastError, jobID)
+	return err
+}
+
+// DeadLetterOrderJob убирает задачу из очереди в dead letter: она остаётся в таблице с последней ошибкой,
+// но больше не забирается обработчиками, пока её не вернут через RequeueOrderJob.
+func (db *Database) DeadLetterOrderJob(ctx context.Context, jobID int, lastError string) error {
+	_, err := db.DB.ExecContext(ctx,
+		`UPDATE order_jobs SET dead_lettered_at=CURRENT_TIMESTAMP, last_error=COALESCE(NULLIF($1, ''), last_error), updated_at=CURRENT_TIMESTAMP
+		WHERE id=$2`,
+		lastError, jobID)
+	return err
+}
+
+// DeadLetteredOrderJobs возвращает до limit задач в dead letter с id больше afterID в порядке id.
+func (db *Database) DeadLetteredOrderJobs(ctx context.Context, afterID, limit int) ([]OrderJob, error) {
+	rows, err := db.DB.QueryContext(ctx,
+		`SELECT j.id, j.attempts, COALESCE(j.last_error, ''), j.created_at, j.dead_lettered_at, o.id, o.order_number, o.status, o.user_id, o.created_at
+		FROM order_jobs j JOIN orders o ON j.order_id=o.id
+		WHERE j.dead_lettered_at IS NOT NULL AND j.id > $1
+		ORDER BY j.id
+		LIMIT $2`,
+		afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []OrderJob
+	for rows.Next() {
+		var job OrderJob
+		err = rows.Scan(&job.ID, &job.Attempts, &job.LastError, &job.CreatedAt, &job.DeadLetteredAt,
+			&job.Order.ID, &job.Order.OrderNumber, &job.Order.Status, &job.Order.UserID, &job.Order.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// RequeueOrderJob возвращает в очередь задачу заказа из dead letter. Счётчик попыток и возраст задачи
+// (enqueued_at) начинаются заново, время создания и последняя ошибка сохраняются. Возвращает ErrNowRows,
+// если у заказа нет задачи в dead letter.
+func (db *Database) RequeueOrderJob(ctx context.Context, orderNumber string) error {
+	res, err := db.DB.ExecContext(ctx,
+		`UPDATE order_jobs j SET dead_lettered_at=NULL, attempts=0, next_attempt_at=CURRENT_TIMESTAMP,
+			enqueued_at=CURRENT_TIMESTAMP, updated_at=CURRENT_TIMESTAMP
+		FROM orders o
+		WHERE j.order_id=o.id AND o.order_number=$1 AND j.dead_lettered_at IS NOT NULL`,
+		orderNumber)
+	if err != nil {
+		return err
+	}
+	updated, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if updated == 0 {
+		return ErrNowRows
+	}
+	return nil
+}
+
+// UpdateOrderStatus переводит заказ в status и начисляет bonus, если статус финальный. Возвращает false,
+// если заказ уже был в финальном статусе и ничего не изменилось.
+func (db *Database) UpdateOrderStatus(ctx context.Context, order *Order, status string, bonus money.Amount) (bool, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE orders SET status=$1 WHERE id=$2 AND status NOT IN ($3, $4)`,
+		status, order.ID, OrderStatusProcessed, OrderStatusInvalid)
+	if err != nil {
+		return false, err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	// заказ в финальном статусе больше не нужно опрашивать
+	if IsFinalOrderStatus(status) {
+		_, err = tx.ExecContext(ctx,
+			`DELETE FROM order_jobs WHERE order_id=$1`,
+			order.ID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// заказ уже финализирован другим обработчиком (например, после истечения JobLease) – повторно не начисляем
+	if updated == 0 {
+		return false, tx.Commit()
+	}
 
 	// если бонусы не начислены, не надо ничего обновлять
 	if bonus != 0 {
@@ -221,7 +477,7 @@ func (db *Database) UpdateOrderStatus(ctx context.Context, order *Order, status
 			`INSERT INTO bonus_transactions(amount, type, user_id, order_id) VALUES($1, $2, $3, $4)`,
 			bonus, AccrualType, order.UserID, order.ID)
 		if err != nil {
-			return err
+			return false, err
 		}
 
 		_, err = tx.ExecContext(ctx,
@@ -229,11 +485,19 @@ func (db *Database) UpdateOrderStatus(ctx context.Context, order *Order, status
 			bonus,
 			order.UserID)
 		if err != nil {
-			return err
+			// баланс складывается в базе, переполнение BIGINT отдаём той же ошибкой, что и money.Amount.Add
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.NumericValueOutOfRange {
+				return false, fmt.Errorf("credit user %d: %w", order.UserID, money.ErrOverflow)
+			}
+			return false, err
 		}
 	}
 
-	return tx.Commit()
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 func (db *Database) FindBonusTransactionsByUserID(ctx context.Context, userID int) ([]BonusTransaction, error) {
@@ -262,10 +526,10 @@ func (db *Database) FindBonusTransactionsByUserID(ctx context.Context, userID in
 	return transactions, nil
 }
 
-func (db *Database) GetWithdrawalSumByUserID(ctx context.Context, userID int) (int, error) {
+func (db *Database) GetWithdrawalSumByUserID(ctx context.Context, userID int) (money.Amount, error) {
 	var total sql.NullInt64
 	err := db.DB.QueryRowContext(ctx,
-		`SELECT SUM(amount) AS total FROM bonus_transactions WHERE user_id=$1 AND type=$2`,
+		`SELECT SUM(amount)::BIGINT AS total FROM bonus_transactions WHERE user_id=$1 AND type=$2`,
 		userID, WithdrawalType).Scan(&total)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -274,16 +538,38 @@ func (db *Database) GetWithdrawalSumByUserID(ctx context.Context, userID int) (i
 		return 0, err
 	}
 
-	return int(total.Int64), err
+	return money.FromCents(total.Int64), err
 }
 
-func (db *Database) SaveWithdrawBonuses(ctx context.Context, userID int, orderNumber string, amount int) error {
-	tx, err := db.DB.Begin()
+// SaveWithdrawBonuses списывает баллы, только если их хватает. Проверка и списание выполняются одним
+// UPDATE, который блокирует строку пользователя до конца транзакции, поэтому параллельные списания
+// не могут увести баланс в минус. При нехватке баллов возвращается ErrInsufficientFunds.
+func (db *Database) SaveWithdrawBonuses(ctx context.Context, userID int, orderNumber string, amount money.Amount) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	res, err := tx.ExecContext(ctx,
+		`UPDATE users SET bonuses=bonuses-$1 WHERE id=$2 AND bonuses >= $1`,
+		amount,
+		userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.CheckViolation {
+			return ErrInsufficientFunds
+		}
+		return err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrInsufficientFunds
+	}
+
 	var orderID int
 	err = tx.QueryRowContext(ctx,
 		`INSERT INTO orders(order_number, status, user_id) VALUES($1, $2, $3) RETURNING id`,
@@ -299,13 +585,250 @@ func (db *Database) SaveWithdrawBonuses(ctx context.Context, userID int, orderNu
 		return err
 	}
 
+	return tx.Commit()
+}
+
+// ReserveIdempotencyKey закрепляет ключ за запросом. Если ключ уже был использован этим пользователем,
+// возвращается сохранённая запись и false. Резерв без ответа старше staleAfter считается брошенным
+// (процесс упал, не дождавшись ответа) и закрепляется за новым запросом.
+func (db *Database) ReserveIdempotencyKey(ctx context.Context, userID int, key, requestHash string, staleAfter time.Duration) (*IdempotencyKey, bool, error) {
+	var id int
+	err := db.DB.QueryRowContext(ctx,
+		`INSERT INTO idempotency_keys(key, user_id, request_hash) VALUES($1, $2, $3)
+		ON CONFLICT (user_id, key) DO UPDATE SET request_hash=EXCLUDED.request_hash, created_at=CURRENT_TIMESTAMP
+		WHERE idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < CURRENT_TIMESTAMP - make_interval(secs => $4)
+		RETURNING id`,
+		key, userID, requestHash, staleAfter.Seconds()).Scan(&id)
+	if err == nil {
+		return &IdempotencyKey{Key: key, UserID: userID, RequestHash: requestHash}, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+
+	var k IdempotencyKey
+	err = db.DB.QueryRowContext(ctx,
+		`SELECT key, user_id, request_hash, COALESCE(status_code, 0), COALESCE(content_type, ''), COALESCE(response_body, ''), created_at
+		FROM idempotency_keys WHERE user_id=$1 AND key=$2`,
+		userID, key).Scan(&k.Key, &k.UserID, &k.RequestHash, &k.StatusCode, &k.ContentType, &k.ResponseBody, &k.CreatedAt)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &k, false, nil
+}
+
+func (db *Database) SaveIdempotencyResponse(ctx context.Context, userID int, key string, statusCode int, contentType, body string) error {
+	_, err := db.DB.ExecContext(ctx,
+		`UPDATE idempotency_keys SET status_code=$1, content_type=$2, response_body=$3 WHERE user_id=$4 AND key=$5`,
+		statusCode, contentType, body, userID, key)
+	return err
+}
+
+func (db *Database) DeleteIdempotencyKey(ctx context.Context, userID int, key string) error {
+	_, err := db.DB.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE user_id=$1 AND key=$2`,
+		userID, key)
+	return err
+}
+
+// DeleteExpiredIdempotencyKeys удаляет ключи идемпотентности старше ttl и возвращает, сколько удалено.
+func (db *Database) DeleteExpiredIdempotencyKeys(ctx context.Context, ttl time.Duration) (int, error) {
+	res, err := db.DB.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE created_at < CURRENT_TIMESTAMP - make_interval(secs => $1)`,
+		ttl.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := res.RowsAffected()
+	return int(deleted), err
+}
+
+// CreateSession сохраняет сессию вместе с её первым refresh-токеном.
+func (db *Database) CreateSession(ctx context.Context, session *Session, refreshTokenHash string) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO sessions(id, user_id, user_agent, ip, expires_at) VALUES($1, $2, $3, $4, $5) RETURNING created_at, last_used_at`,
+		session.ID, session.UserID, session.UserAgent, session.IP, session.ExpiresAt).Scan(&session.CreatedAt, &session.LastUsedAt)
+	if err != nil {
+		return err
+	}
+
 	_, err = tx.ExecContext(ctx,
-		`UPDATE users SET bonuses=bonuses-$1 WHERE id=$2`,
-		amount,
-		userID)
+		`INSERT INTO refresh_tokens(token_hash, session_id) VALUES($1, $2)`,
+		refreshTokenHash, session.ID)
 	if err != nil {
 		return err
 	}
 
 	return tx.Commit()
 }
+
+// RotateRefreshToken обменивает refresh-токен на новый и продлевает сессию до expiresAt.
+// Каждый токен можно использовать один раз: повторное предъявление уже обменянного токена означает,
+// что его украли, поэтому сессия отзывается целиком и возвращается ErrRefreshTokenReused.
+// Для неизвестного токена, отозванной или истёкшей сессии возвращается ErrNowRows.
+func (db *Database) RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*Session, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		s         Session
+		usedAt    sql.NullTime
+		revokedAt sql.NullTime
+		expired   bool
+	)
+	err = tx.QueryRowContext(ctx,
+		`SELECT s.id, s.user_id, u.login, COALESCE(s.user_agent, ''), COALESCE(s.ip, ''), s.created_at, s.last_used_at, s.expires_at,
+			s.revoked_at, s.expires_at <= CURRENT_TIMESTAMP, rt.used_at
+		FROM refresh_tokens rt
+			JOIN sessions s ON rt.session_id=s.id
+			JOIN users u ON s.user_id=u.id
+		WHERE rt.token_hash=$1
+		FOR UPDATE OF rt, s`,
+		oldHash).Scan(&s.ID, &s.UserID, &s.Login, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt,
+		&revokedAt, &expired, &usedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNowRows
+		}
+		return nil, err
+	}
+	if revokedAt.Valid || expired {
+		return nil, ErrNowRows
+	}
+
+	if usedAt.Valid {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE sessions SET revoked_at=CURRENT_TIMESTAMP WHERE id=$1`,
+			s.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, err
+		}
+		return &s, ErrRefreshTokenReused
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE refresh_tokens SET used_at=CURRENT_TIMESTAMP WHERE token_hash=$1`,
+		oldHash)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO refresh_tokens(token_hash, session_id) VALUES($1, $2)`,
+		newHash, s.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.QueryRowContext(ctx,
+		`UPDATE sessions SET last_used_at=CURRENT_TIMESTAMP, expires_at=$1 WHERE id=$2 RETURNING last_used_at, expires_at`,
+		expiresAt, s.ID).Scan(&s.LastUsedAt, &s.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, tx.Commit()
+}
+
+func (db *Database) FindActiveSessionsByUserID(ctx context.Context, userID int) ([]Session, error) {
+	rows, err := db.DB.QueryContext(ctx,
+		`SELECT id, user_id, COALESCE(user_agent, ''), COALESCE(ip, ''), created_at, last_used_at, expires_at
+		FROM sessions
+		WHERE user_id=$1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY last_used_at DESC`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var s Session
+		err = rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession отзывает активную сессию пользователя. Если такой нет, возвращается ErrNowRows.
+func (db *Database) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	res, err := db.DB.ExecContext(ctx,
+		`UPDATE sessions SET revoked_at=CURRENT_TIMESTAMP WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL`,
+		sessionID, userID)
+	if err != nil {
+		return err
+	}
+	revoked, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return ErrNowRows
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions отзывает все активные сессии пользователя, кроме exceptSessionID.
+func (db *Database) RevokeOtherSessions(ctx context.Context, userID int, exceptSessionID string) (int, error) {
+	res, err := db.DB.ExecContext(ctx,
+		`UPDATE sessions SET revoked_at=CURRENT_TIMESTAMP WHERE user_id=$1 AND id!=$2 AND revoked_at IS NULL`,
+		userID, exceptSessionID)
+	if err != nil {
+		return 0, err
+	}
+	revoked, err := res.RowsAffected()
+
+	return int(revoked), err
+}
+
+func (db *Database) SaveFailedLogin(ctx context.Context, attempt *FailedLogin) error {
+	_, err := db.DB.ExecContext(ctx,
+		`INSERT INTO failed_logins(login, ip, user_agent, reason) VALUES($1, $2, $3, $4)`,
+		attempt.Login, attempt.IP, attempt.UserAgent, attempt.Reason)
+	return err
+}
+
+// DeleteExpiredFailedLogins удаляет из аудита неудачные входы старше ttl.
+func (db *Database) DeleteExpiredFailedLogins(ctx context.Context, ttl time.Duration) (int, error) {
+	res, err := db.DB.ExecContext(ctx,
+		`DELETE FROM failed_logins WHERE created_at < CURRENT_TIMESTAMP - make_interval(secs => $1)`,
+		ttl.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := res.RowsAffected()
+	return int(deleted), err
+}
+
+// OrderQueueStats возвращает число задач в очереди заказов, число задач, время которых подошло,
+// и число задач в dead letter. Задачи в dead letter в первые два числа не входят.
+func (db *Database) OrderQueueStats(ctx context.Context) (int, int, int, error) {
+	var total, ready, deadLettered int
+	err := db.DB.QueryRowContext(ctx,
+		`SELECT COUNT(*) FILTER (WHERE dead_lettered_at IS NULL),
+			COUNT(*) FILTER (WHERE dead_lettered_at IS NULL AND next_attempt_at <= CURRENT_TIMESTAMP),
+			COUNT(*) FILTER (WHERE dead_lettered_at IS NOT NULL)
+		FROM order_jobs`).Scan(&total, &ready, &deadLettered)
+	return total, ready, deadLettered, err
+}
@@ -1,6 +1,10 @@
 package store
 
-import "time"
+import (
+	"time"
+
+	"github.com/arseniy96/bonus-program/internal/services/money"
+)
 
 const (
 	AccrualType           = "accrual"
@@ -13,12 +17,10 @@ const (
 )
 
 type User struct {
-	ID         int
-	Login      string
-	Password   string
-	Token      string
-	Bonuses    int
-	TokenExpAt time.Time
+	ID       int
+	Login    string
+	Password string
+	Bonuses  money.Amount
 }
 
 type Order struct {
@@ -26,16 +28,88 @@ type Order struct {
 	OrderNumber string
 	Status      string
 	UserID      int
-	BonusAmount int
+	BonusAmount money.Amount
 	CreatedAt   time.Time
 }
 
 type BonusTransaction struct {
 	ID          int
-	Amount      int
+	Amount      money.Amount
 	Type        string
 	UserID      int
 	OrderID     int
 	OrderNumber string
 	CreatedAt   time.Time
 }
+
+// OrderJob — задача на опрос системы начислений по заказу.
+type OrderJob struct {
+	ID            int
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	// RequestID — ID запроса, загрузившего заказ; пуст для задач, восстановленных при старте
+	RequestID string
+	// TraceParent — спан запроса, загрузившего заказ, в формате заголовка traceparent
+	TraceParent string
+	// CreatedAt — когда заказ впервые поставлен в очередь
+	CreatedAt time.Time
+	// EnqueuedAt — с какого момента отсчитывается OrderMaxAge; при повторной постановке после dead letter
+	// отсчёт начинается заново
+	EnqueuedAt time.Time
+	// DeadLetteredAt — когда задача исчерпала попытки; заполняется только в DeadLetteredOrderJobs
+	DeadLetteredAt time.Time
+	Order          Order
+}
+
+// Session — вход пользователя с одного устройства. Login заполняется только там, где он нужен
+// для выпуска токена доступа.
+type Session struct {
+	ID         string
+	UserID     int
+	Login      string
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+}
+
+// IdempotencyKey — сохранённый результат запроса с заголовком Idempotency-Key.
+// StatusCode равен 0, пока исходный запрос ещё выполняется.
+type IdempotencyKey struct {
+	Key          string
+	UserID       int
+	RequestHash  string
+	StatusCode   int
+	ContentType  string
+	ResponseBody string
+	CreatedAt    time.Time
+}
+
+// FailedLogin — запись аудита о неудачной попытке входа.
+type FailedLogin struct {
+	Login     string
+	IP        string
+	UserAgent string
+	Reason    string
+}
+
+// RecoveryPage — результат обработки одной страницы незавершённых заказов.
+type RecoveryPage struct {
+	LastID   int
+	Scanned  int
+	Enqueued int
+}
+
+// MigrationStatus — версия схемы в базе. Version может быть больше Expected, если базу уже обновила
+// более новая реплика.
+type MigrationStatus struct {
+	Version  uint
+	Dirty    bool
+	Expected uint
+}
+
+func IsFinalOrderStatus(status string) bool {
+	return status == OrderStatusProcessed || status == OrderStatusInvalid
+}
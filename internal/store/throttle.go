@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ThrottleStore хранит счётчики неудачных попыток и блокировки в Postgres, чтобы все реплики
+// сервиса видели одно и то же состояние. Реализует throttle.Store. Время хранится в колонках TIMESTAMP
+// в UTC, поэтому now переводится в UTC перед записью и сравнением.
+type ThrottleStore struct {
+	db *Database
+}
+
+func (db *Database) ThrottleStore() *ThrottleStore {
+	return &ThrottleStore{db: db}
+}
+
+func (s *ThrottleStore) AddFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	now = now.UTC()
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM throttle_failures WHERE key=$1 AND failed_at<=$2`,
+		key, now.Add(-window))
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO throttle_failures(key, failed_at) VALUES($1, $2)`,
+		key, now)
+	if err != nil {
+		return 0, err
+	}
+	var failures int
+	err = tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM throttle_failures WHERE key=$1`,
+		key).Scan(&failures)
+	if err != nil {
+		return 0, err
+	}
+
+	return failures, tx.Commit()
+}
+
+func (s *ThrottleStore) Lock(ctx context.Context, key string, now time.Time, forgetAfter time.Duration, lockout func(n int) time.Duration) (time.Time, error) {
+	now = now.UTC()
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	var (
+		lockouts    int
+		lockedUntil time.Time
+	)
+	err = tx.QueryRowContext(ctx,
+		`SELECT lockouts, locked_until FROM throttle_lockouts WHERE key=$1 FOR UPDATE`,
+		key).Scan(&lockouts, &lockedUntil)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, err
+	}
+	if lockedUntil.After(now) {
+		// параллельный запрос уже заблокировал ключ
+		return lockedUntil, nil
+	}
+	if now.Sub(lockedUntil) > forgetAfter {
+		lockouts = 0
+	}
+	lockouts++
+	lockedUntil = now.Add(lockout(lockouts))
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO throttle_lockouts(key, lockouts, locked_until) VALUES($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET lockouts=EXCLUDED.lockouts, locked_until=EXCLUDED.locked_until`,
+		key, lockouts, lockedUntil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM throttle_failures WHERE key=$1`,
+		key)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return lockedUntil, tx.Commit()
+}
+
+func (s *ThrottleStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	var lockedUntil time.Time
+	err := s.db.DB.QueryRowContext(ctx,
+		`SELECT locked_until FROM throttle_lockouts WHERE key=$1`,
+		key).Scan(&lockedUntil)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, err
+	}
+
+	return lockedUntil, nil
+}
+
+func (s *ThrottleStore) Reset(ctx context.Context, key string) error {
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM throttle_failures WHERE key=$1`, key); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM throttle_lockouts WHERE key=$1`, key); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteExpired удаляет неудачи и блокировки ключей с префиксом prefix, которые уже ни на что не влияют:
+// Lock всё равно обнулил бы номер блокировки, закончившейся больше forgetAfter назад.
+func (s *ThrottleStore) DeleteExpired(ctx context.Context, prefix string, now time.Time, window, forgetAfter time.Duration) (int, error) {
+	now = now.UTC()
+	res, err := s.db.DB.ExecContext(ctx,
+		`DELETE FROM throttle_failures WHERE failed_at<=$1 AND left(key, length($2))=$2`,
+		now.Add(-window), prefix)
+	if err != nil {
+		return 0, err
+	}
+	failures, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	res, err = s.db.DB.ExecContext(ctx,
+		`DELETE FROM throttle_lockouts WHERE locked_until<$1 AND left(key, length($2))=$2`,
+		now.Add(-forgetAfter), prefix)
+	if err != nil {
+		return 0, err
+	}
+	lockouts, err := res.RowsAffected()
+
+	return int(failures + lockouts), err
+}
@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var throttleNow = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func lockout(n int) time.Duration {
+	return time.Duration(n) * time.Minute
+}
+
+func TestThrottleStore_AddFailure(t *testing.T) {
+	db, mock := newMockDatabase(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM throttle_failures WHERE key=$1 AND failed_at<=$2`).
+		WithArgs("login:gopher", throttleNow.Add(-time.Minute)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO throttle_failures(key, failed_at) VALUES($1, $2)`).
+		WithArgs("login:gopher", throttleNow).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT COUNT(*) FROM throttle_failures WHERE key=$1`).
+		WithArgs("login:gopher").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectCommit()
+
+	failures, err := db.ThrottleStore().AddFailure(context.Background(), "login:gopher", throttleNow, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 2, failures)
+}
+
+func TestThrottleStore_Lock(t *testing.T) {
+	const (
+		selectLockout = `SELECT lockouts, locked_until FROM throttle_lockouts WHERE key=$1 FOR UPDATE`
+		upsertLockout = `INSERT INTO throttle_lockouts(key, lockouts, locked_until) VALUES($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET lockouts=EXCLUDED.lockouts, locked_until=EXCLUDED.locked_until`
+		deleteFailures = `DELETE FROM throttle_failures WHERE key=$1`
+	)
+
+	tests := []struct {
+		name   string
+		rows   *sqlmock.Rows
+		want   time.Time
+		locked bool
+		n      int
+	}{
+		{
+			name: "first lockout",
+			rows: sqlmock.NewRows([]string{"lockouts", "locked_until"}),
+			want: throttleNow.Add(time.Minute),
+			n:    1,
+		},
+		{
+			name: "next lockout is longer",
+			rows: sqlmock.NewRows([]string{"lockouts", "locked_until"}).AddRow(2, throttleNow.Add(-time.Minute)),
+			want: throttleNow.Add(3 * time.Minute),
+			n:    3,
+		},
+		{
+			name: "old lockout is forgotten",
+			rows: sqlmock.NewRows([]string{"lockouts", "locked_until"}).AddRow(2, throttleNow.Add(-2*time.Hour)),
+			want: throttleNow.Add(time.Minute),
+			n:    1,
+		},
+		{
+			// параллельный запрос уже заблокировал ключ — блокировка не продлевается
+			name:   "already locked",
+			rows:   sqlmock.NewRows([]string{"lockouts", "locked_until"}).AddRow(1, throttleNow.Add(time.Minute)),
+			want:   throttleNow.Add(time.Minute),
+			locked: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDatabase(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(selectLockout).WithArgs("login:gopher").WillReturnRows(tt.rows)
+			if tt.locked {
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectExec(upsertLockout).WithArgs("login:gopher", tt.n, tt.want).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(deleteFailures).WithArgs("login:gopher").
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectCommit()
+			}
+
+			until, err := db.ThrottleStore().Lock(context.Background(), "login:gopher", throttleNow, time.Hour, lockout)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, until)
+		})
+	}
+}
+
+func TestThrottleStore_LockedUntil(t *testing.T) {
+	const query = `SELECT locked_until FROM throttle_lockouts WHERE key=$1`
+
+	db, mock := newMockDatabase(t)
+	mock.ExpectQuery(query).WithArgs("login:gopher").
+		WillReturnRows(sqlmock.NewRows([]string{"locked_until"}).AddRow(throttleNow))
+	mock.ExpectQuery(query).WithArgs("login:other").
+		WillReturnRows(sqlmock.NewRows([]string{"locked_until"}))
+
+	until, err := db.ThrottleStore().LockedUntil(context.Background(), "login:gopher")
+	require.NoError(t, err)
+	assert.Equal(t, throttleNow, until)
+
+	// ключ без блокировок
+	until, err = db.ThrottleStore().LockedUntil(context.Background(), "login:other")
+	require.NoError(t, err)
+	assert.True(t, until.IsZero())
+}
+
+func TestThrottleStore_Reset(t *testing.T) {
+	db, mock := newMockDatabase(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM throttle_failures WHERE key=$1`).WithArgs("login:gopher").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM throttle_lockouts WHERE key=$1`).WithArgs("login:gopher").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, db.ThrottleStore().Reset(context.Background(), "login:gopher"))
+}
+
+func TestThrottleStore_DeleteExpired(t *testing.T) {
+	db, mock := newMockDatabase(t)
+	mock.ExpectExec(`DELETE FROM throttle_failures WHERE failed_at<=$1 AND left(key, length($2))=$2`).
+		WithArgs(throttleNow.Add(-time.Minute), "login:").
+		WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectExec(`DELETE FROM throttle_lockouts WHERE locked_until<$1 AND left(key, length($2))=$2`).
+		WithArgs(throttleNow.Add(-time.Hour), "login:").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	deleted, err := db.ThrottleStore().DeleteExpired(context.Background(), "login:", throttleNow, time.Minute, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 7, deleted)
+}